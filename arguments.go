@@ -58,6 +58,7 @@ type ArgInfo struct {
 	Flag          bool
 	DefaultOption string
 	Choices       []string
+	Autocomplete  bool
 	Regex         *regexp2.Regexp
 }
 
@@ -175,11 +176,103 @@ func (cI *CommandInfo) AddChoices(arg string, choices []string) *CommandInfo {
 	return cI
 }
 
+// SetAutocomplete
+// Marks an arg as autocompleted, so Discord asks the command's autocomplete handler for its choices
+// Discord doesn't allow autocomplete on args that have fixed choices
+func (cI *CommandInfo) SetAutocomplete(arg string) *CommandInfo {
+	v, ok := cI.Arguments.Get(arg)
+	if ok {
+		vv := v.(*ArgInfo)
+		vv.Autocomplete = true
+		cI.Arguments.Set(arg, vv)
+	} else {
+		log.Errorf("Unable to get argument %s in SetAutocomplete", arg)
+		return cI
+	}
+	return cI
+}
+
 func (cI *CommandInfo) SetTyping(isTyping bool) *CommandInfo {
 	cI.IsTyping = isTyping
 	return cI
 }
 
+// SetDeferReply
+// Sets whether slash command invocations are acknowledged with a deferred response before the command runs
+// Use this for commands that may take longer than the 3 seconds Discord allows for a response
+func (cI *CommandInfo) SetDeferReply(deferReply bool) *CommandInfo {
+	cI.DeferReply = deferReply
+	return cI
+}
+
+// SetDeferEphemeral
+// Sets whether the deferred response of a command that uses SetDeferReply is only shown to the user
+// Whether a response is ephemeral is decided when it is deferred, so commands with ephemeral responses should set this
+func (cI *CommandInfo) SetDeferEphemeral(ephemeral bool) *CommandInfo {
+	cI.DeferEphemeral = ephemeral
+	return cI
+}
+
+// SetHidden
+// Sets whether the command is left out of help listings. Hidden commands can still be run
+func (cI *CommandInfo) SetHidden(hidden bool) *CommandInfo {
+	cI.Hidden = hidden
+	return cI
+}
+
+// SetDefaultMemberPermissions
+// Sets the permissions members need to see and use the slash command, e.g. discordgo.PermissionBanMembers
+// Server admins can still change this per guild. 0 hides the command from everyone but admins
+// Invalid permission bitmasks are logged and ignored
+func (cI *CommandInfo) SetDefaultMemberPermissions(permissions int64) *CommandInfo {
+	if permissions < 0 || permissions&^discordgo.PermissionAll != 0 {
+		log.Errorf("Invalid default member permissions %d for command %s", permissions, cI.Trigger)
+		return cI
+	}
+	cI.DefaultMemberPermissions = &permissions
+	return cI
+}
+
+// SetNSFW
+// Sets whether the slash command is age-restricted, so it can only be used in age-restricted channels
+func (cI *CommandInfo) SetNSFW(nsfw bool) *CommandInfo {
+	cI.NSFW = nsfw
+	return cI
+}
+
+// SetGuildOnly
+// Sets whether the command can only be used in a guild
+func (cI *CommandInfo) SetGuildOnly(guildOnly bool) *CommandInfo {
+	cI.GuildOnly = guildOnly
+	return cI
+}
+
+// SetDMOnly
+// Sets whether the command can only be used in DMs
+func (cI *CommandInfo) SetDMOnly(dmOnly bool) *CommandInfo {
+	cI.DMOnly = dmOnly
+	return cI
+}
+
+// AddLocalization
+// Adds a translated name and description for the slash command in the given locale (e.g. "de", "es-ES")
+// A blank name or description keeps the default for that locale
+func (cI *CommandInfo) AddLocalization(locale string, name string, description string) *CommandInfo {
+	if name != "" {
+		if cI.NameLocalizations == nil {
+			cI.NameLocalizations = make(map[discordgo.Locale]string)
+		}
+		cI.NameLocalizations[discordgo.Locale(locale)] = name
+	}
+	if description != "" {
+		if cI.DescriptionLocalizations == nil {
+			cI.DescriptionLocalizations = make(map[discordgo.Locale]string)
+		}
+		cI.DescriptionLocalizations[discordgo.Locale(locale)] = description
+	}
+	return cI
+}
+
 //todo subcommand stuff
 //// BindToChoice
 //// Bind an arg to choice (subcmd)
@@ -189,8 +282,16 @@ func (cI *CommandInfo) SetTyping(isTyping bool) *CommandInfo {
 
 // CreateAppOptSt
 // Creates an ApplicationOptionsStruct for all the args.
+// This is used to register a child command as a sub command of its parent's slash command
 func (cI *CommandInfo) CreateAppOptSt() *discordgo.ApplicationCommandOption {
-	return &discordgo.ApplicationCommandOption{}
+	return &discordgo.ApplicationCommandOption{
+		Type:                     discordgo.ApplicationCommandOptionSubCommand,
+		Name:                     cI.Trigger,
+		NameLocalizations:        cI.NameLocalizations,
+		Description:              cI.Description,
+		DescriptionLocalizations: cI.DescriptionLocalizations,
+		Options:                  createSlashCommandOptions(cI),
+	}
 }
 
 // -- Argument Parser --
@@ -258,8 +359,8 @@ func findAllOptionArgs(argString []string, keys []string, infoArgs *orderedmap.O
 	var indexes []int
 
 	// (semi) Brute force method
+	// Every arg that is found consumes its tokens from argString, so the next arg always starts at the front
 	// First lets find all required args
-	currentPos := 0
 	for i, v := range keys {
 		// error handling
 		iA, ok := infoArgs.Get(v)
@@ -270,17 +371,24 @@ func findAllOptionArgs(argString []string, keys []string, infoArgs *orderedmap.O
 		}
 		vv := iA.(*ArgInfo)
 		if vv.Match == ArgContent {
-			return *args, true, argString, keys
+			// Drop the keys that were already found, so the content goes to this arg
+			return *args, true, argString, RemoveItems(keys, indexes)
 		}
 		if vv.Required {
+			if len(argString) == 0 {
+				// Nothing left to consume, so fall back to the default
+				(*args)[v] = handleArgOption(vv.DefaultOption, *vv)
+				indexes = append(indexes, i)
+				continue
+			}
 			if vv.TypeGuard != String {
 				var value string
 				value, argString = findTypeGuard(strings.Join(argString, " "), argString, vv.TypeGuard)
 				(*args)[v] = handleArgOption(value, *vv)
 				indexes = append(indexes, i)
-			} else if checkTypeGuard(argString[currentPos], vv.TypeGuard) {
-				(*args)[v] = handleArgOption(argString[currentPos], *vv)
-				currentPos++
+			} else if checkTypeGuard(argString[0], vv.TypeGuard) {
+				(*args)[v] = handleArgOption(argString[0], *vv)
+				argString = argString[1:]
 				indexes = append(indexes, i)
 			} else {
 				(*args)[v] = handleArgOption(vv.DefaultOption, *vv)
@@ -295,9 +403,7 @@ func findAllOptionArgs(argString []string, keys []string, infoArgs *orderedmap.O
 	// We also reset some values that we reuse
 	//if
 	modKeys = RemoveItems(keys, indexes)
-	argString = argString[currentPos:]
 	indexes = nil
-	currentPos = 0
 	// Return early if the argument parser has found all args
 	if argString == nil || len(argString) == 0 || len(modKeys) == 0 || modKeys == nil {
 		return *args, false, argString, modKeys
@@ -323,8 +429,8 @@ func findAllOptionArgs(argString []string, keys []string, infoArgs *orderedmap.O
 			modKeys = RemoveItems(modKeys, indexes)
 			return *args, true, argString, modKeys
 		}
-		// Break early if current pos is the length of the array
-		if currentPos == len(argString) {
+		// Break early if every token has been consumed
+		if len(argString) == 0 {
 			break
 		}
 		if vv.TypeGuard != String {
@@ -332,9 +438,9 @@ func findAllOptionArgs(argString []string, keys []string, infoArgs *orderedmap.O
 			value, argString = findTypeGuard(strings.Join(argString, " "), argString, vv.TypeGuard)
 			(*args)[v] = handleArgOption(value, *vv)
 			indexes = append(indexes, i)
-		} else if checkTypeGuard(argString[currentPos], vv.TypeGuard) {
-			(*args)[v] = handleArgOption(argString[currentPos], *vv)
-			currentPos++
+		} else if checkTypeGuard(argString[0], vv.TypeGuard) {
+			(*args)[v] = handleArgOption(argString[0], *vv)
+			argString = argString[1:]
 			indexes = append(indexes, i)
 		} else {
 
@@ -344,6 +450,18 @@ func findAllOptionArgs(argString []string, keys []string, infoArgs *orderedmap.O
 	return *args, false, createSplitString(modifiedArgString), modKeys
 }
 
+// findIdInArray
+// Find the first element of the array that is a raw Discord ID, and remove it from the array
+// The "id" regex is anchored, so each element is checked on its own rather than the joined input
+func findIdInArray(array []string) (string, []string) {
+	for _, v := range array {
+		if isMatch, _ := MentionStringRegexes["id"].MatchString(v); isMatch {
+			return v, RemoveItem(array, v)
+		}
+	}
+	return "", array
+}
+
 func findTypeGuard(input string, array []string, typeguard ArgTypeGuards) (string, []string) {
 	switch typeguard {
 	case Int:
@@ -359,22 +477,22 @@ func findTypeGuard(input string, array []string, typeguard ArgTypeGuards) (strin
 	case Channel:
 		if match, isMatch := MentionStringRegexes["channel"].FindStringMatch(input); isMatch == nil && match != nil {
 			return match.String(), RemoveItem(array, match.String())
-		} else if match, isMatch := MentionStringRegexes["id"].FindStringMatch(input); isMatch == nil && match != nil {
-			return match.String(), RemoveItem(array, match.String())
+		} else if id, remaining := findIdInArray(array); id != "" {
+			return id, remaining
 		}
 		return "", array
 	case Role:
 		if match, isMatch := MentionStringRegexes["role"].FindStringMatch(input); isMatch == nil && match != nil {
 			return match.String(), RemoveItem(array, match.String())
-		} else if match, isMatch := MentionStringRegexes["id"].FindStringMatch(input); isMatch == nil && match != nil {
-			return match.String(), RemoveItem(array, match.String())
+		} else if id, remaining := findIdInArray(array); id != "" {
+			return id, remaining
 		}
 		return "", array
 	case User:
 		if match, isMatch := MentionStringRegexes["user"].FindStringMatch(input); isMatch == nil && match != nil {
 			return match.String(), RemoveItem(array, match.String())
-		} else if match, isMatch := MentionStringRegexes["id"].FindStringMatch(input); isMatch == nil && match != nil {
-			return match.String(), RemoveItem(array, match.String())
+		} else if id, remaining := findIdInArray(array); id != "" {
+			return id, remaining
 		}
 		return "", array
 	case ArrString:
@@ -756,3 +874,25 @@ func (ag CommandArg) RoleValue(s *discordgo.Session, gID string) (*discordgo.Rol
 	}
 	return r, nil
 }
+
+// MessageValue is a utility function for fetching the message a message link argument points to
+// Returns a message struct, or a nil value
+func (ag CommandArg) MessageValue(s *discordgo.Session) (*discordgo.Message, error) {
+	link := ag.StringValue()
+	if match, err := TypeGuard["message_url"].FindStringMatch(link); err != nil || match == nil {
+		return nil, errors.New("invalid message link")
+	}
+	if s == nil {
+		return nil, errors.New("no session")
+	}
+
+	// Links are formatted as .../channels/<guild id>/<channel id>/<message id>
+	split := strings.Split(strings.TrimRight(link, "/"), "/")
+	channelID, messageID := split[len(split)-2], split[len(split)-1]
+
+	m, err := s.State.Message(channelID, messageID)
+	if err != nil {
+		return s.ChannelMessage(channelID, messageID)
+	}
+	return m, nil
+}
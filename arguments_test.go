@@ -0,0 +1,201 @@
+package framework
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCheckTypeGuardIds(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"16 digits", "1234567890123456", false},
+		{"17 digits", "12345678901234567", true},
+		{"18 digits", "123456789012345678", true},
+		{"19 digits", "1234567890123456789", true},
+		{"20 digits", "12345678901234567890", true},
+		{"21 digits", "123456789012345678901", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, guard := range []ArgTypeGuards{User, Role, Channel} {
+				if got := checkTypeGuard(tt.id, guard); got != tt.want {
+					t.Errorf("checkTypeGuard(%q, %s) = %v, want %v", tt.id, guard, got, tt.want)
+				}
+			}
+			// CleanId only has a lower bound, so every valid ID must survive it
+			if tt.want && CleanId(tt.id) != tt.id {
+				t.Errorf("CleanId(%q) = %q", tt.id, CleanId(tt.id))
+			}
+		})
+	}
+}
+
+func TestCheckTypeGuardMentions(t *testing.T) {
+	const (
+		userMention     = "<@123456789012345678>"
+		nickMention     = "<@!123456789012345678>"
+		roleMention     = "<@&123456789012345678>"
+		channelMention  = "<#123456789012345678>"
+		bareAngleNumber = "<123456789012345678>"
+	)
+
+	tests := []struct {
+		input string
+		guard ArgTypeGuards
+		want  bool
+	}{
+		{userMention, User, true},
+		{nickMention, User, true},
+		{roleMention, User, false},
+		{channelMention, User, false},
+		{roleMention, Role, true},
+		{userMention, Role, false},
+		{channelMention, Role, false},
+		{channelMention, Channel, true},
+		{roleMention, Channel, false},
+		{userMention, Channel, false},
+		{bareAngleNumber, Channel, false},
+	}
+
+	for _, tt := range tests {
+		if got := checkTypeGuard(tt.input, tt.guard); got != tt.want {
+			t.Errorf("checkTypeGuard(%q, %s) = %v, want %v", tt.input, tt.guard, got, tt.want)
+		}
+	}
+}
+
+func TestFindTypeGuardRoleIsNotChannel(t *testing.T) {
+	array := []string{"<@&123456789012345678>"}
+	value, remaining := findTypeGuard(array[0], array, Channel)
+	if value != "" {
+		t.Errorf("findTypeGuard found channel %q in a role mention", value)
+	}
+	if !reflect.DeepEqual(remaining, array) {
+		t.Errorf("findTypeGuard consumed %v, want %v left", remaining, array)
+	}
+}
+
+func TestMessageURLDomains(t *testing.T) {
+	const path = "/channels/123456789012345678/223456789012345678/323456789012345678"
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://discord.com" + path, true},
+		{"https://canary.discord.com" + path, true},
+		{"https://ptb.discord.com" + path, true},
+		{"https://discordapp.com" + path, true},
+		{"https://example.com" + path, false},
+		{"https://discord.com/channels/123", false},
+	}
+
+	for _, tt := range tests {
+		if got := checkTypeGuard(tt.link, Message); got != tt.want {
+			t.Errorf("checkTypeGuard(%q, Message) = %v, want %v", tt.link, got, tt.want)
+		}
+
+		array := []string{"see", tt.link}
+		value, _ := findTypeGuard("see "+tt.link, array, Message)
+		if got := value == tt.link; got != tt.want {
+			t.Errorf("findTypeGuard(%q, Message) = %q", tt.link, value)
+		}
+	}
+}
+
+func TestMessageValue(t *testing.T) {
+	const (
+		channelId = "223456789012345678"
+		messageId = "323456789012345678"
+	)
+
+	s, fake := newFakeSession(func(req *http.Request) (int, string) {
+		if req.URL.Path == "/api/v9/channels/"+channelId+"/messages/"+messageId {
+			return http.StatusOK, `{"id":"` + messageId + `","channel_id":"` + channelId + `"}`
+		}
+		return http.StatusNotFound, `{"code":10008,"message":"Unknown Message"}`
+	})
+
+	for _, host := range []string{"discord.com", "canary.discord.com", "ptb.discord.com", "discordapp.com"} {
+		arg := CommandArg{Value: "https://" + host + "/channels/123456789012345678/" + channelId + "/" + messageId}
+		message, err := arg.MessageValue(s)
+		if err != nil {
+			t.Errorf("MessageValue for %s: %s", host, err)
+			continue
+		}
+		if message.ID != messageId || message.ChannelID != channelId {
+			t.Errorf("MessageValue for %s = %s/%s, want %s/%s", host, message.ChannelID, message.ID, channelId, messageId)
+		}
+	}
+
+	requests := len(fake.Requests())
+	if _, err := (CommandArg{Value: "not a link"}).MessageValue(s); err == nil {
+		t.Error("MessageValue accepted an invalid link")
+	}
+	if len(fake.Requests()) != requests {
+		t.Error("MessageValue made a request for an invalid link")
+	}
+}
+
+func TestParseArgumentsRequiredUserThenString(t *testing.T) {
+	info := CreateCommandInfo("test", "", true, "").
+		AddArg("user", User, ArgOption, "", true, "").
+		AddArg("reason", String, ArgOption, "", true, "")
+
+	tests := []struct {
+		name   string
+		input  string
+		user   string
+		reason string
+	}{
+		{"mention first", "<@123456789012345678> spam", "<@123456789012345678>", "spam"},
+		{"raw id first", "123456789012345678 spam", "123456789012345678", "spam"},
+		{"quoted string", "<@123456789012345678> \"spam and scams\"", "<@123456789012345678>", "spam and scams"},
+		{"string missing", "<@123456789012345678>", "<@123456789012345678>", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := *ParseArguments(tt.input, info.Arguments)
+			if got := args["user"].StringValue(); got != tt.user {
+				t.Errorf("user = %q, want %q", got, tt.user)
+			}
+			if got := args["reason"].StringValue(); got != tt.reason {
+				t.Errorf("reason = %q, want %q", got, tt.reason)
+			}
+		})
+	}
+}
+
+func TestRemoveItems(t *testing.T) {
+	slice := []string{"a", "b", "c", "d"}
+	tests := []struct {
+		name    string
+		indexes []int
+		want    []string
+	}{
+		{"first two", []int{0, 1}, []string{"c", "d"}},
+		{"non-adjacent", []int{1, 3}, []string{"a", "c"}},
+		{"all", []int{0, 1, 2, 3}, []string{}},
+		{"unordered and repeated", []int{3, 0, 3}, []string{"b", "c"}},
+		{"out of range", []int{-1, 4}, []string{"a", "b", "c", "d"}},
+		{"none", nil, []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RemoveItems(slice, tt.indexes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RemoveItems(%v, %v) = %v, want %v", slice, tt.indexes, got, tt.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(slice, []string{"a", "b", "c", "d"}) {
+		t.Errorf("RemoveItems modified the original slice: %v", slice)
+	}
+}
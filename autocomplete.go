@@ -0,0 +1,170 @@
+package framework
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// autocomplete.go
+// This file contains everything required for commands to suggest choices while a slash command arg is being typed
+
+// autocompleteHandlers
+// A map of command triggers to the function that provides their autocomplete choices
+// This is private so that other commands cannot modify it
+var autocompleteHandlers = make(map[string]BotFunction)
+
+// argAutocompleteHandlers
+// A map of command triggers to a map of arg names to the function that provides that arg's autocomplete choices
+// These take precedence over the handler for the whole command
+var argAutocompleteHandlers = make(map[string]map[string]BotFunction)
+
+// AddAutoCompleteHandler
+// Add the function that provides autocomplete choices for a command
+// Args must be marked with SetAutocomplete for Discord to ask for their choices
+func AddAutoCompleteHandler(trigger string, handler BotFunction) {
+	// Command triggers are case-insensitive, and the handler is looked up by the lowercase trigger
+	commandsLock.Lock()
+	autocompleteHandlers[strings.ToLower(trigger)] = handler
+	commandsLock.Unlock()
+}
+
+// AddArgAutoCompleteHandler
+// Add the function that provides autocomplete choices for a single arg of a command
+// The arg may belong to a sub command; it is matched by name no matter how deeply it is nested
+func AddArgAutoCompleteHandler(trigger string, arg string, handler BotFunction) {
+	trigger = strings.ToLower(trigger)
+	commandsLock.Lock()
+	defer commandsLock.Unlock()
+	if argAutocompleteHandlers[trigger] == nil {
+		argAutocompleteHandlers[trigger] = make(map[string]BotFunction)
+	}
+	argAutocompleteHandlers[trigger][arg] = handler
+}
+
+// getAutoCompleteHandler
+// Get the autocomplete handler for an arg of a command, falling back to the handler for the whole command
+func getAutoCompleteHandler(trigger string, arg string) (BotFunction, bool) {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+	if handler, ok := argAutocompleteHandlers[trigger][arg]; ok {
+		return handler, true
+	}
+	handler, ok := autocompleteHandlers[trigger]
+	return handler, ok
+}
+
+// findFocusedOption
+// Find the focused option, searching recursively since sub command options are nested in the sub command (group)
+func findFocusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, option := range options {
+		if option.Focused {
+			return option
+		}
+		if focused := findFocusedOption(option.Options); focused != nil {
+			return focused
+		}
+	}
+	return nil
+}
+
+// FocusedOption
+// Get the slash command option the user is currently typing in, during autocomplete
+// Returns nil outside of autocomplete
+func (ctx *Context) FocusedOption() *discordgo.ApplicationCommandInteractionDataOption {
+	if ctx.Interaction == nil || ctx.Interaction.Type != discordgo.InteractionApplicationCommandAutocomplete {
+		return nil
+	}
+	return findFocusedOption(ctx.Interaction.ApplicationCommandData().Options)
+}
+
+// SendAutocompleteChoices
+// Respond to an autocomplete interaction with the given choices
+// Discord shows at most 25 choices
+func (ctx *Context) SendAutocompleteChoices(choices []*discordgo.ApplicationCommandOptionChoice) error {
+	return Session.InteractionRespond(ctx.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
+// maxAutocompleteChoices
+// The most choices Discord will show for an autocompleted arg
+const maxAutocompleteChoices = 25
+
+// SendFilteredAutocomplete
+// Respond to an autocomplete interaction with the values from all that match what the user typed so far
+// Matching is case-insensitive. Values starting with the input are listed first, followed by values containing it
+func (ctx *Context) SendFilteredAutocomplete(focusedArg string, all []string) error {
+	input := ""
+	if focused := ctx.FocusedOption(); focused != nil && focused.Name == focusedArg {
+		// The partial input is a string, even for number args
+		input = strings.ToLower(fmt.Sprint(focused.Value))
+	}
+
+	var prefixed, contained []string
+	for _, value := range all {
+		lower := strings.ToLower(value)
+		if strings.HasPrefix(lower, input) {
+			prefixed = append(prefixed, value)
+		} else if strings.Contains(lower, input) {
+			contained = append(contained, value)
+		}
+	}
+
+	matches := append(prefixed, contained...)
+	if len(matches) > maxAutocompleteChoices {
+		matches = matches[:maxAutocompleteChoices]
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(matches))
+	for i, match := range matches {
+		choices[i] = &discordgo.ApplicationCommandOptionChoice{
+			Name:  match,
+			Value: match,
+		}
+	}
+	return ctx.SendAutocompleteChoices(choices)
+}
+
+// handleAutoComplete
+// Handles an autocomplete interaction by running the autocomplete handler of the command
+func handleAutoComplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	trigger := strings.ToLower(i.ApplicationCommandData().Name)
+
+	focusedName := ""
+	if focused := findFocusedOption(i.ApplicationCommandData().Options); focused != nil {
+		focusedName = focused.Name
+	}
+
+	handler, ok := getAutoCompleteHandler(trigger, focusedName)
+	if !ok {
+		log.Warningf("Received autocomplete for command \"%s\", which has no autocomplete handler", trigger)
+		return
+	}
+	command, ok := getCommand(trigger)
+	if !ok {
+		return
+	}
+	if command.Info.IsParent {
+		command, _, _ = findSubCommand(command, i.ApplicationCommandData().Options)
+	}
+
+	defer handleSlashCommandError(*i.Interaction)
+	handler(&Context{
+		Guild:       getGuild(i.GuildID),
+		Cmd:         command.Info,
+		Args:        *ParseInteractionArgs(i.ApplicationCommandData().Options),
+		Interaction: i.Interaction,
+		Message: &discordgo.Message{
+			Member:    i.Member,
+			Author:    interactionUser(i.Interaction),
+			ChannelID: i.ChannelID,
+			GuildID:   i.GuildID,
+			Content:   "",
+		},
+	})
+}
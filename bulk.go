@@ -0,0 +1,94 @@
+package framework
+
+import (
+	"fmt"
+)
+
+// bulk.go
+// This file contains batch versions of the moderator, whitelist and ignore list functions, for setting up a guild quickly
+
+// applyBatch
+// Run a single-ID function for every ID in a batch, skipping IDs that appear more than once
+// Returns the (cleaned) IDs the function succeeded for, and an error for each ID it failed for
+// Each ID goes through the single-ID function, so rules like the whitelist and ignore lists being
+// mutually exclusive hold across the whole batch
+func applyBatch(ids []string, fn func(id string) error) (done []string, errs []error) {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		key := CleanId(id)
+		if key == "" {
+			key = id
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if err := fn(id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		done = append(done, key)
+	}
+	return done, errs
+}
+
+// AddMods
+// Add several user or role IDs as bot moderators. See AddMod
+func (g *Guild) AddMods(ids ...string) (added []string, errs []error) {
+	return applyBatch(ids, g.AddMod)
+}
+
+// RemoveMods
+// Remove several user or role IDs from the bot moderators. See RemoveMod
+func (g *Guild) RemoveMods(ids ...string) (removed []string, errs []error) {
+	return applyBatch(ids, g.RemoveMod)
+}
+
+// AddMembersOrRolesToWhitelist
+// Add several member or role IDs to the whitelist. See AddMemberOrRoleToWhitelist
+func (g *Guild) AddMembersOrRolesToWhitelist(ids ...string) (added []string, errs []error) {
+	return applyBatch(ids, g.AddMemberOrRoleToWhitelist)
+}
+
+// RemoveMembersOrRolesFromWhitelist
+// Remove several member or role IDs from the whitelist. See RemoveMemberOrRoleFromWhitelist
+func (g *Guild) RemoveMembersOrRolesFromWhitelist(ids ...string) (removed []string, errs []error) {
+	return applyBatch(ids, g.RemoveMemberOrRoleFromWhitelist)
+}
+
+// AddMembersOrRolesToIgnored
+// Add several member or role IDs to the ignore list. See AddMemberOrRoleToIgnored
+func (g *Guild) AddMembersOrRolesToIgnored(ids ...string) (added []string, errs []error) {
+	return applyBatch(ids, g.AddMemberOrRoleToIgnored)
+}
+
+// RemoveMembersOrRolesFromIgnored
+// Remove several member or role IDs from the ignore list. See RemoveMemberOrRoleFromIgnored
+func (g *Guild) RemoveMembersOrRolesFromIgnored(ids ...string) (removed []string, errs []error) {
+	return applyBatch(ids, g.RemoveMemberOrRoleFromIgnored)
+}
+
+// AddChannelsToWhitelist
+// Add several channels to the channel whitelist. See AddChannelToWhitelist
+func (g *Guild) AddChannelsToWhitelist(ids ...string) (added []string, errs []error) {
+	return applyBatch(ids, g.AddChannelToWhitelist)
+}
+
+// RemoveChannelsFromWhitelist
+// Remove several channels from the channel whitelist. See RemoveChannelFromWhitelist
+func (g *Guild) RemoveChannelsFromWhitelist(ids ...string) (removed []string, errs []error) {
+	return applyBatch(ids, g.RemoveChannelFromWhitelist)
+}
+
+// AddChannelsToIgnored
+// Add several channels to the ignored channels. See AddChannelToIgnored
+func (g *Guild) AddChannelsToIgnored(ids ...string) (added []string, errs []error) {
+	return applyBatch(ids, g.AddChannelToIgnored)
+}
+
+// RemoveChannelsFromIgnored
+// Remove several channels from the ignored channels. See RemoveChannelFromIgnored
+func (g *Guild) RemoveChannelsFromIgnored(ids ...string) (removed []string, errs []error) {
+	return applyBatch(ids, g.RemoveChannelFromIgnored)
+}
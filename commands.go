@@ -1,11 +1,13 @@
 package framework
 
 import (
+	"errors"
 	"github.com/QPixel/orderedmap"
 	"github.com/bwmarrin/discordgo"
-	"runtime"
 	"runtime/debug"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,19 +23,58 @@ var (
 	Utility    Group = "utility"
 )
 
+// groupOrder
+// The position of each registered group in help output, lower comes first
+var groupOrder = map[Group]int{
+	Moderation: 0,
+	Utility:    1,
+}
+
+// RegisterGroup
+// Register a group, or change its position, for ordering in a help command
+// Groups are listed by GetGroups from the lowest order to the highest
+func RegisterGroup(name Group, order int) {
+	groupOrder[name] = order
+}
+
+// GetGroups
+// Get every registered group, in the order they should be listed in a help command
+func GetGroups() []Group {
+	groups := make([]Group, 0, len(groupOrder))
+	for group := range groupOrder {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groupOrder[groups[i]] != groupOrder[groups[j]] {
+			return groupOrder[groups[i]] < groupOrder[groups[j]]
+		}
+		return groups[i] < groups[j]
+	})
+	return groups
+}
+
 // CommandInfo
 // The definition of a command's info. This is everything about the command, besides the function it will run
 type CommandInfo struct {
-	Aliases     []string               // Aliases for the normal trigger
-	Arguments   *orderedmap.OrderedMap // Arguments for the command
-	Description string                 // A short description of what the command does
-	Group       Group                  // The group this command belongs to
-	ParentID    string                 // The ID of the parent command
-	Public      bool                   // Whether non-admins and non-mods can use this command
-	IsTyping    bool                   // Whether the command will show a typing thing when ran.
-	IsParent    bool                   // If the command is the parent of a subcommand tree
-	IsChild     bool                   // If the command is the child
-	Trigger     string                 // The string that will trigger the command
+	Aliases                  []string                    // Aliases for the normal trigger
+	Arguments                *orderedmap.OrderedMap      // Arguments for the command
+	DefaultMemberPermissions *int64                      // The permissions members need to see the slash command, nil for everyone
+	Description              string                      // A short description of what the command does
+	DescriptionLocalizations map[discordgo.Locale]string // Translated descriptions of the slash command
+	Group                    Group                       // The group this command belongs to
+	NameLocalizations        map[discordgo.Locale]string // Translated names of the slash command
+	ParentID                 string                      // The ID of the parent command
+	Public                   bool                        // Whether non-admins and non-mods can use this command
+	IsTyping                 bool                        // Whether the command will show a typing thing when ran (prefix commands only)
+	DeferReply               bool                        // Whether slash command invocations are acknowledged before the command runs
+	DeferEphemeral           bool                        // Whether the deferred acknowledgement (and so the response) is only shown to the user
+	IsParent                 bool                        // If the command is the parent of a subcommand tree
+	IsChild                  bool                        // If the command is the child
+	GuildOnly                bool                        // If the command can only be used in a guild
+	DMOnly                   bool                        // If the command can only be used in DMs
+	Hidden                   bool                        // If the command is left out of help listings (it can still be run)
+	NSFW                     bool                        // If the slash command is age-restricted
+	Trigger                  string                      // The string that will trigger the command
 }
 
 // Context
@@ -45,6 +86,11 @@ type Context struct {
 	Args        Arguments
 	Message     *discordgo.Message
 	Interaction *discordgo.Interaction
+
+	// deferred is whether the interaction was already acknowledged with a deferred response
+	deferred bool
+	// deferredEphemeral is whether that deferred response is only shown to the user
+	deferredEphemeral bool
 }
 
 // BotFunction
@@ -82,6 +128,49 @@ var commandAliases = make(map[string]string)
 // This is also private so other commands cannot modify it
 var slashCommands = make(map[string]discordgo.ApplicationCommand)
 
+// commandsLock
+// Guards commands, commandAliases, slashCommands and the autocomplete handlers
+// Commands can be removed while the bot is running, while every message and interaction handler reads these maps
+var commandsLock sync.RWMutex
+
+// getCommand
+// Get a registered command by its trigger, which is case-insensitive
+func getCommand(trigger string) (Command, bool) {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+	command, ok := commands[strings.ToLower(trigger)]
+	return command, ok
+}
+
+// getCommandByAlias
+// Get a registered command by one of its aliases (which include its trigger), which are case-insensitive
+func getCommandByAlias(alias string) (Command, bool) {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+	trigger, ok := commandAliases[strings.ToLower(alias)]
+	if !ok {
+		return Command{}, false
+	}
+	command, ok := commands[trigger]
+	return command, ok
+}
+
+// unknownCommandHandler
+// The function that is run when a prefix command doesn't match any command or alias, if one is set
+var unknownCommandHandler BotFunction
+
+// UnknownCommandArgs
+// The argument that holds everything after the trigger, in the context passed to the unknown command handler
+const UnknownCommandArgs = "args"
+
+// SetUnknownCommandHandler
+// Set a function that runs when a message starts with the prefix, but doesn't match any command or alias
+// ctx.Cmd.Trigger is the (lowercase) trigger that was used, and ctx.Args[UnknownCommandArgs] holds the rest of the message
+// The guild's disabled, whitelist and ignore checks apply to it like any other command. Pass nil to remove it
+func SetUnknownCommandHandler(handler BotFunction) {
+	unknownCommandHandler = handler
+}
+
 // commandsGC
 var commandsGC = 0
 
@@ -95,19 +184,87 @@ func AddCommand(info *CommandInfo, function BotFunction) {
 		Info:     *info,
 		Function: function,
 	}
-	// adds a alias to a map; command aliases are case-sensitive
+
+	commandsLock.Lock()
+	defer commandsLock.Unlock()
+	// adds a alias to a map; command aliases are case-insensitive, and point to the lowercase trigger
 	for _, alias := range info.Aliases {
+		alias = strings.ToLower(alias)
 		if _, ok := commandAliases[alias]; ok {
 			log.Errorf("Alias was already registered %s for command %s", alias, info.Trigger)
 			continue
 		}
-		alias = strings.ToLower(alias)
-		commandAliases[alias] = info.Trigger
+		commandAliases[alias] = strings.ToLower(info.Trigger)
 	}
 	// Add the command to the map; command triggers are case-insensitive
 	commands[strings.ToLower(info.Trigger)] = command
 }
 
+// DefaultHandler
+// The name of the handler that runs when no other named handler of a command matches, see AddCommandHandler
+// If a command has no handler with this name, the function given to AddCommand is its default handler
+const DefaultHandler = "default"
+
+// AutocompleteHandlerPrefix
+// Handler names starting with this prefix provide autocomplete choices instead of running the command
+// "ac:<arg>" provides the choices for a single arg, and "ac:" alone provides them for the whole command
+const AutocompleteHandlerPrefix = "ac:"
+
+// commandHandlers
+// A map of command triggers to a map of handler names to the handler, see AddCommandHandler
+var commandHandlers = make(map[string]map[string]BotFunction)
+
+// AddCommandHandler
+// Add a named handler to a command. Handler names are a convention, like the "ac:" prefix of autocomplete handlers:
+// A sub command's name, e.g. "add", runs when that sub command of the slash command is chosen, e.g. "/tag add"
+// A sub command in a group is named "<group> <sub command>", e.g. "channel set" for "/config channel set"
+// If there is no handler with that name, the handler named after just the sub command ("set") is used instead
+// "default" (DefaultHandler) runs when no other handler matches, instead of the function given to AddCommand
+// A name starting with "ac:" (AutocompleteHandlerPrefix) adds an autocomplete handler, see AddArgAutoCompleteHandler
+// Named handlers take precedence over child commands with the same trigger
+func AddCommandHandler(trigger string, name string, handler BotFunction) {
+	if strings.HasPrefix(name, AutocompleteHandlerPrefix) {
+		if arg := strings.TrimPrefix(name, AutocompleteHandlerPrefix); arg != "" {
+			AddArgAutoCompleteHandler(trigger, arg, handler)
+		} else {
+			AddAutoCompleteHandler(trigger, handler)
+		}
+		return
+	}
+
+	trigger = strings.ToLower(trigger)
+	commandsLock.Lock()
+	defer commandsLock.Unlock()
+	if commandHandlers[trigger] == nil {
+		commandHandlers[trigger] = make(map[string]BotFunction)
+	}
+	commandHandlers[trigger][strings.ToLower(name)] = handler
+}
+
+// getCommandHandler
+// Get the first named handler of a command that matches one of the given names
+func getCommandHandler(trigger string, names ...string) (BotFunction, bool) {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+	handlers := commandHandlers[strings.ToLower(trigger)]
+	for _, name := range names {
+		if handler, ok := handlers[strings.ToLower(name)]; ok {
+			return handler, true
+		}
+	}
+	return nil, false
+}
+
+// defaultFunction
+// Get the function to run for a command when no named handler matches
+// This is the command's "default" handler if it has one, otherwise the function given to AddCommand
+func (c Command) defaultFunction() BotFunction {
+	if handler, ok := getCommandHandler(c.Info.Trigger, DefaultHandler); ok {
+		return handler
+	}
+	return c.Function
+}
+
 // AddChildCommand
 // Adds a child command to the bot.
 func AddChildCommand(info *CommandInfo, function BotFunction) {
@@ -127,24 +284,81 @@ func AddChildCommand(info *CommandInfo, function BotFunction) {
 // AddSlashCommand
 // Adds a slash command to the bot
 // Allows for separation between normal commands and slash commands
+// Parent commands are registered with their child commands as sub commands, so add the children first
 func AddSlashCommand(info *CommandInfo) {
-	if !info.IsParent || !info.IsChild {
-		s := createSlashCommandStruct(info)
-		slashCommands[strings.ToLower(info.Trigger)] = *s
-		return
-	}
+	st := buildSlashCommand(info)
+	commandsLock.Lock()
+	slashCommands[strings.ToLower(info.Trigger)] = *st
+	commandsLock.Unlock()
+}
+
+// buildSlashCommand
+// Creates the slash command struct for a command, with its child commands as sub commands if it is a parent
+func buildSlashCommand(info *CommandInfo) *discordgo.ApplicationCommand {
 	if info.IsParent {
-		s := createSlashSubCmdStruct(info, childCommands[info.Trigger])
-		slashCommands[strings.ToLower(info.Trigger)] = *s
-		return
+		return createSlashSubCmdStruct(info, childCommands[strings.ToLower(info.Trigger)])
 	}
+	return createSlashCommandStruct(info)
+}
+
+// findRegisteredSlashCommand
+// Find a slash command that is registered with Discord by its name
+// Returns nil if there is no such command
+func findRegisteredSlashCommand(guildId string, name string) (*discordgo.ApplicationCommand, error) {
+	registered, err := Session.ApplicationCommands(Session.State.User.ID, guildId)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range registered {
+		if strings.EqualFold(v.Name, name) {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdateSlashCommand
+// Update a single slash command that is already registered, from the current definition of the command
+// This is a lot cheaper than registering every slash command again after changing one of them
+func UpdateSlashCommand(guildId string, name string) error {
+	command, ok := getCommand(name)
+	if !ok {
+		return errors.New("command " + name + " does not exist")
+	}
+
+	existing, err := findRegisteredSlashCommand(guildId, name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("slash command " + name + " is not registered")
+	}
+
+	st := buildSlashCommand(&command.Info)
+	_, err = Session.ApplicationCommandEdit(Session.State.User.ID, guildId, existing.ID, st)
+	if err != nil {
+		return err
+	}
+
+	commandsLock.Lock()
+	slashCommands[strings.ToLower(name)] = *st
+	commandsLock.Unlock()
+	return nil
 }
 
 // AddSlashCommands
 // Defaults to adding Global slash commands
 // Currently hard coded to guild commands for testing
 func AddSlashCommands(guildId string, c chan string) {
+	// Copy the slash commands, so the lock isn't held while registering them
+	commandsLock.RLock()
+	toRegister := make([]discordgo.ApplicationCommand, 0, len(slashCommands))
 	for _, v := range slashCommands {
+		toRegister = append(toRegister, v)
+	}
+	commandsLock.RUnlock()
+
+	for _, v := range toRegister {
 		_, err := Session.ApplicationCommandCreate(Session.State.User.ID, guildId, &v)
 		if err != nil {
 			c <- "Unable to register slash commands :/"
@@ -157,11 +371,67 @@ func AddSlashCommands(guildId string, c chan string) {
 	return
 }
 
+// RemoveSlashCommand
+// Delete a single slash command from Discord by its name; nothing happens if it isn't registered
+// If unregister is true, the command is also removed from the bot, so it stops handling the command as well
+func RemoveSlashCommand(guildId string, name string, unregister bool) error {
+	existing, err := findRegisteredSlashCommand(guildId, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		err = Session.ApplicationCommandDelete(Session.State.User.ID, guildId, existing.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if unregister {
+		removeCommand(name)
+	}
+	return nil
+}
+
+// removeCommand
+// Remove a command, its aliases, and its slash command and autocomplete handlers from the bot
+func removeCommand(trigger string) {
+	trigger = strings.ToLower(trigger)
+	commandsLock.Lock()
+	defer commandsLock.Unlock()
+	delete(commands, trigger)
+	delete(slashCommands, trigger)
+	delete(autocompleteHandlers, trigger)
+	delete(argAutocompleteHandlers, trigger)
+	delete(commandHandlers, trigger)
+	for alias, aliasTrigger := range commandAliases {
+		if aliasTrigger == trigger {
+			delete(commandAliases, alias)
+		}
+	}
+}
+
 // GetCommands
 // Provide a way to read commands without making it possible to modify their functions
 func GetCommands() map[string]CommandInfo {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+	list := make(map[string]CommandInfo)
+	for x, y := range commands {
+		list[x] = y.Info
+	}
+	return list
+}
+
+// GetVisibleCommands
+// Like GetCommands, but leaves out hidden commands, for use in help listings
+func GetVisibleCommands() map[string]CommandInfo {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
 	list := make(map[string]CommandInfo)
 	for x, y := range commands {
+		if y.Info.Hidden {
+			continue
+		}
 		list[x] = y.Info
 	}
 	return list
@@ -190,7 +460,8 @@ func commandHandler(session *discordgo.Session, message *discordgo.MessageCreate
 		return
 	}
 	// Only do further checks if the user is not a bot admin
-	if !IsAdmin(message.Author.ID) {
+	// DMs have no guild, so the guild-scoped checks are skipped entirely
+	if !IsAdmin(message.Author.ID) && message.GuildID != "" {
 		// Ignore the command if it is globally disabled
 		if g.IsGloballyDisabled(*trigger) {
 			return
@@ -214,16 +485,51 @@ func commandHandler(session *discordgo.Session, message *discordgo.MessageCreate
 
 	//Get the command to run
 	// Error Checking
-	command, ok := commands[commandAliases[*trigger]]
+	command, ok := getCommandByAlias(*trigger)
 	if !ok {
+		// Built-in commands take precedence, so a custom command can't shadow one
+		if message.GuildID != "" && runCustomCommand(g, message.Message, *trigger, *argString) {
+			return
+		}
+
+		// Let the unknown command handler deal with it, if there is one
+		if unknownCommandHandler != nil {
+			defer handleCommandError(g.ID, channel.ID, message.Author.ID)
+			applyMiddleware(unknownCommandHandler)(&Context{
+				Guild: g,
+				Cmd: CommandInfo{
+					Trigger:   *trigger,
+					Arguments: orderedmap.New(),
+				},
+				Args: Arguments{
+					UnknownCommandArgs: CommandArg{Value: *argString},
+				},
+				Message: message.Message,
+			})
+			return
+		}
+
 		log.Errorf("Command was not found")
+		// Offer the closest command, in case it was a typo
+		if message.GuildID != "" {
+			suggestCommand(g, message.ChannelID, *trigger)
+		}
+		return
+	}
+
+	// Make sure the command is allowed in this context (guild or DM)
+	if reason := command.Info.contextRejection(message.GuildID == ""); reason != "" {
+		_, err = Session.ChannelMessageSendEmbed(message.ChannelID, CreateEmbed(ColorFailure, "Error", reason, nil))
+		if err != nil {
+			log.Errorf("Failed to send context rejection for command %s: %s", command.Info.Trigger, err)
+		}
 		return
 	}
 	// Check if the command is public, or if the current user is a bot moderator
 	// Bot admins supercede both checks
 	if IsAdmin(message.Author.ID) || command.Info.Public || g.IsMod(message.Author.ID) {
 		// Run the command with the necessary context
-		if command.Info.IsTyping && g.Info.ResponseChannelId == "" {
+		if command.Info.IsTyping && g.GetResponseChannel(command.Info.Group) == "" {
 			_ = Session.ChannelTyping(message.ChannelID)
 		}
 		// The command is valid, so now we need to delete the invoking message if that is configured
@@ -234,16 +540,22 @@ func commandHandler(session *discordgo.Session, message *discordgo.MessageCreate
 			}
 		}
 
+		recordGuildUsage(g, command.Info.Trigger)
+
 		defer handleCommandError(g.ID, channel.ID, message.Author.ID)
 		if command.Info.IsParent {
-			handleChildCommand(*argString, command, message.Message, g)
+			measureCommand(command.Info.Trigger, false, func() {
+				handleChildCommand(*argString, command, message.Message, g)
+			})
 			return
 		}
-		command.Function(&Context{
-			Guild:   g,
-			Cmd:     command.Info,
-			Args:    *ParseArguments(*argString, command.Info.Arguments),
-			Message: message.Message,
+		measureCommand(command.Info.Trigger, false, func() {
+			applyMiddleware(command.defaultFunction())(&Context{
+				Guild:   g,
+				Cmd:     command.Info,
+				Args:    *ParseArguments(*argString, command.Info.Arguments),
+				Message: message.Message,
+			})
 		})
 		// Makes sure that variables ran in ParseArguments are gone.
 		if commandsGC == 25 && commandsGC > 25 {
@@ -258,12 +570,25 @@ func commandHandler(session *discordgo.Session, message *discordgo.MessageCreate
 }
 
 // -- Helper Methods
+
+// contextRejection
+// Check if the command is allowed to run in a guild or a DM
+// Returns the reason it was rejected, or a blank string if it is allowed
+func (cI *CommandInfo) contextRejection(isDM bool) string {
+	if cI.GuildOnly && isDM {
+		return "This command can only be used in a server."
+	}
+	if cI.DMOnly && !isDM {
+		return "This command can only be used in DMs."
+	}
+	return ""
+}
 func handleChildCommand(argString string, command Command, message *discordgo.Message, g *Guild) {
 	split := strings.SplitN(argString, " ", 2)
 
-	childCmd, ok := childCommands[command.Info.Trigger][split[0]]
+	childCmd, ok := childCommands[strings.ToLower(command.Info.Trigger)][split[0]]
 	if !ok {
-		command.Function(&Context{
+		applyMiddleware(command.defaultFunction())(&Context{
 			Guild:   g,
 			Cmd:     command.Info,
 			Args:    nil,
@@ -272,7 +597,7 @@ func handleChildCommand(argString string, command Command, message *discordgo.Me
 		return
 	}
 	if len(split) < 2 {
-		childCmd.Function(&Context{
+		applyMiddleware(childCmd.Function)(&Context{
 			Guild:   g,
 			Cmd:     childCmd.Info,
 			Args:    *ParseArguments("", childCmd.Info.Arguments),
@@ -280,7 +605,7 @@ func handleChildCommand(argString string, command Command, message *discordgo.Me
 		})
 		return
 	}
-	childCmd.Function(&Context{
+	applyMiddleware(childCmd.Function)(&Context{
 		Guild:   g,
 		Cmd:     childCmd.Info,
 		Args:    *ParseArguments(split[1], childCmd.Info.Arguments),
@@ -289,14 +614,17 @@ func handleChildCommand(argString string, command Command, message *discordgo.Me
 	return
 }
 
+// handleCommandError
+// Recover from a panic in a command, report it to the bot admins, and let the user know something went wrong
 func handleCommandError(gID string, cId string, uId string) {
 	if r := recover(); r != nil {
 		log.Warningf("Recovering from panic: %s", r)
 		log.Warningf("Sending Error report to admins")
-		SendErrorReport(gID, cId, uId, "Error!", r.(runtime.Error))
+		SendErrorReport(gID, cId, uId, "Error!", recoveredError(r), panicStack())
 		message, err := Session.ChannelMessageSend(cId, "Error!")
 		if err != nil {
 			log.Errorf("err sending message %s", err)
+			return
 		}
 		time.Sleep(5 * time.Second)
 		_ = Session.ChannelMessageDelete(cId, message.ID)
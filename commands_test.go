@@ -0,0 +1,45 @@
+package framework
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestRemoveCommandWhileHandling(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		trigger := "removed" + strconv.Itoa(i)
+		info := CreateCommandInfo(trigger, "", true, "")
+		info.Aliases = []string{trigger + "-alias"}
+		AddCommand(info, func(ctx *Context) {})
+		AddAutoCompleteHandler(trigger, func(ctx *Context) {})
+
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			removeCommand(trigger)
+		}()
+		go func() {
+			defer wg.Done()
+			getCommandByAlias(trigger + "-alias")
+			getAutoCompleteHandler(trigger, "")
+		}()
+		go func() {
+			defer wg.Done()
+			_ = GetCommands()
+			closestCommand("removed")
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 20; i++ {
+		trigger := "removed" + strconv.Itoa(i)
+		if IsCommand(trigger) {
+			t.Errorf("command %s wasn't removed", trigger)
+		}
+		if _, ok := getCommandByAlias(trigger + "-alias"); ok {
+			t.Errorf("the alias of command %s wasn't removed", trigger)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package framework
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// components.go
+// This file contains the handling of message components (buttons, select menus) and helpers built on top of them
+
+// componentHandlers
+// A map of component custom IDs to the function that handles them
+// This is private so that other commands cannot modify it
+var componentHandlers = make(map[string]BotFunction)
+
+// componentHandlersLock
+// Guards componentHandlers, since handlers are added and removed while interactions are being handled
+var componentHandlersLock sync.RWMutex
+
+// AddComponentHandler
+// Register a function to run when a component with the given custom ID is used
+// Registering a custom ID a second time replaces the previous handler
+func AddComponentHandler(customId string, handler BotFunction) {
+	componentHandlersLock.Lock()
+	defer componentHandlersLock.Unlock()
+	componentHandlers[customId] = handler
+}
+
+// RemoveComponentHandler
+// Remove the handler for the given custom ID, if there is one
+func RemoveComponentHandler(customId string) {
+	componentHandlersLock.Lock()
+	defer componentHandlersLock.Unlock()
+	delete(componentHandlers, customId)
+}
+
+// getComponentHandler
+// Get the handler registered for the given custom ID
+func getComponentHandler(customId string) (BotFunction, bool) {
+	componentHandlersLock.RLock()
+	defer componentHandlersLock.RUnlock()
+	handler, ok := componentHandlers[customId]
+	return handler, ok
+}
+
+// -- Confirmation Prompts --
+
+// confirmButtons
+// Create the row of Confirm/Cancel buttons for a confirmation prompt
+func confirmButtons(confirmId string, cancelId string, disabled bool) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Confirm",
+					Style:    discordgo.SuccessButton,
+					CustomID: confirmId,
+					Disabled: disabled,
+				},
+				discordgo.Button{
+					Label:    "Cancel",
+					Style:    discordgo.DangerButton,
+					CustomID: cancelId,
+					Disabled: disabled,
+				},
+			},
+		},
+	}
+}
+
+// sendPrompt
+// Send a message with components to wherever the context was invoked from
+// Returns a function that replaces the components on the sent message
+func (ctx *Context) sendPrompt(embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) (func([]discordgo.MessageComponent) error, error) {
+	if ctx.Interaction != nil {
+		// Answer the interaction with the prompt, unless the command already responded to it
+		err := Session.InteractionRespond(ctx.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     []*discordgo.MessageEmbed{embed},
+				Components: components,
+			},
+		})
+		if err == nil {
+			return func(c []discordgo.MessageComponent) error {
+				_, err := Session.InteractionResponseEdit(ctx.Interaction, &discordgo.WebhookEdit{Components: &c})
+				return err
+			}, nil
+		}
+
+		message, err := Session.FollowupMessageCreate(ctx.Interaction, true, &discordgo.WebhookParams{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return func(c []discordgo.MessageComponent) error {
+			_, err := Session.FollowupMessageEdit(ctx.Interaction, message.ID, &discordgo.WebhookEdit{Components: &c})
+			return err
+		}, nil
+	}
+
+	message, err := Session.ChannelMessageSendComplex(ctx.Message.ChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func(c []discordgo.MessageComponent) error {
+		_, err := Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:         message.ID,
+			Channel:    message.ChannelID,
+			Embeds:     message.Embeds,
+			Components: c,
+		})
+		return err
+	}, nil
+}
+
+// Confirm
+// Ask the invoking user a yes/no question with Confirm/Cancel buttons, and wait for them to answer
+// Clicks from other users are ignored. If nobody answers before the timeout, false is returned
+// Either way, the buttons are disabled once the prompt is resolved
+func (ctx *Context) Confirm(question string, timeout time.Duration) (bool, error) {
+	author := ctx.Author()
+	if author == nil {
+		return false, errors.New("cannot confirm without an invoking user")
+	}
+	authorId := author.ID
+
+	// Custom IDs have to be unique, so multiple prompts can be open at the same time
+	nonce := strconv.FormatInt(time.Now().UnixNano(), 36)
+	confirmId := "confirm_" + nonce + "_yes"
+	cancelId := "confirm_" + nonce + "_no"
+
+	result := make(chan bool, 1)
+	handler := func(c *Context) {
+		user := interactionUser(c.Interaction)
+		if user == nil || user.ID != authorId {
+			ephemeralErrorResponse(c.Interaction, "Only the person who ran this command can answer this prompt.")
+			return
+		}
+
+		var embeds []*discordgo.MessageEmbed
+		if c.Interaction.Message != nil {
+			embeds = c.Interaction.Message.Embeds
+		}
+		err := Session.InteractionRespond(c.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     embeds,
+				Components: confirmButtons(confirmId, cancelId, true),
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to disable confirmation buttons: %s", err)
+		}
+
+		// Only the first answer counts
+		select {
+		case result <- c.Interaction.MessageComponentData().CustomID == confirmId:
+		default:
+		}
+	}
+
+	AddComponentHandler(confirmId, handler)
+	AddComponentHandler(cancelId, handler)
+	defer RemoveComponentHandler(confirmId)
+	defer RemoveComponentHandler(cancelId)
+
+	edit, err := ctx.sendPrompt(CreateEmbed(ColorSuccess, "Are you sure?", question, nil), confirmButtons(confirmId, cancelId, false))
+	if err != nil {
+		return false, err
+	}
+
+	select {
+	case confirmed := <-result:
+		return confirmed, nil
+	case <-time.After(timeout):
+		if err = edit(confirmButtons(confirmId, cancelId, true)); err != nil {
+			log.Errorf("Failed to disable confirmation buttons: %s", err)
+		}
+		return false, nil
+	}
+}
@@ -0,0 +1,31 @@
+package framework
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTimeRegexesPreferMonths(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"2mo", []string{"2mo"}},
+		{"1mo2w", []string{"1mo", "2w"}},
+		{"5m", []string{"5m"}},
+		{"5m2mo", []string{"5m", "2mo"}},
+	}
+
+	for _, tt := range tests {
+		if got := FindAllString(TimeRegexes["all"], tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindAllString(all, %q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	if isMatch, _ := TimeRegexes["months"].MatchString("2mo"); !isMatch {
+		t.Error("months regex doesn't match 2mo")
+	}
+	if isMatch, _ := TimeRegexes["minutes"].MatchString("2mo"); isMatch {
+		t.Error("minutes regex matches 2mo")
+	}
+}
@@ -0,0 +1,121 @@
+package framework
+
+import (
+	"errors"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// context.go
+// This file contains helpers on Context that work the same way for prefix commands and interactions
+
+// guildId
+// Get the ID of the guild the context was invoked in, or a blank string if it was invoked outside a guild
+func (ctx *Context) guildId() string {
+	if ctx.Interaction != nil {
+		return ctx.Interaction.GuildID
+	}
+	if ctx.Message != nil {
+		return ctx.Message.GuildID
+	}
+	return ""
+}
+
+// IsDM
+// Check if the context was invoked outside a guild, i.e. in a DM with the bot,
+// or through a user-installed app in a DM or group DM
+// Note that ctx.Guild is not nil in DMs, so it can't be used for this check
+func (ctx *Context) IsDM() bool {
+	return ctx.guildId() == ""
+}
+
+// InGuild
+// Check if the context was invoked in a guild. This is always the opposite of IsDM
+func (ctx *Context) InGuild() bool {
+	return !ctx.IsDM()
+}
+
+// Author
+// Get the user who invoked the context, or nil if it is unknown
+// For interactions this is the user who used the command or component, in guilds and DMs alike
+func (ctx *Context) Author() *discordgo.User {
+	if ctx.Interaction != nil {
+		// For components, the message is the one the component is attached to, which was sent by the bot
+		return interactionUser(ctx.Interaction)
+	}
+	if ctx.Message != nil {
+		return ctx.Message.Author
+	}
+	return nil
+}
+
+// ChannelID
+// Get the ID of the channel the context was invoked in, or a blank string if it is unknown
+func (ctx *Context) ChannelID() string {
+	if ctx.Interaction != nil {
+		return ctx.Interaction.ChannelID
+	}
+	if ctx.Message != nil {
+		return ctx.Message.ChannelID
+	}
+	return ""
+}
+
+// targetData
+// Get the data of the context menu command the context was invoked by
+func (ctx *Context) targetData() (discordgo.ApplicationCommandInteractionData, error) {
+	if ctx.Interaction == nil || ctx.Interaction.Type != discordgo.InteractionApplicationCommand {
+		return discordgo.ApplicationCommandInteractionData{}, errors.New("context was not invoked by an application command")
+	}
+	data := ctx.Interaction.ApplicationCommandData()
+	if data.TargetID == "" || data.Resolved == nil {
+		return data, errors.New("context was not invoked by a context menu command")
+	}
+	return data, nil
+}
+
+// TargetUser
+// Get the user a user context menu command was used on
+func (ctx *Context) TargetUser() (*discordgo.User, error) {
+	data, err := ctx.targetData()
+	if err != nil {
+		return nil, err
+	}
+	user, ok := data.Resolved.Users[data.TargetID]
+	if !ok {
+		return nil, errors.New("context menu command has no target user")
+	}
+	return user, nil
+}
+
+// TargetMessage
+// Get the message a message context menu command was used on
+func (ctx *Context) TargetMessage() (*discordgo.Message, error) {
+	data, err := ctx.targetData()
+	if err != nil {
+		return nil, err
+	}
+	message, ok := data.Resolved.Messages[data.TargetID]
+	if !ok {
+		return nil, errors.New("context menu command has no target message")
+	}
+	return message, nil
+}
+
+// Reply
+// Send a success response with a title and description in one call
+// The response is routed like any other Response: interactions are answered, and messages go to the response channel
+func (ctx *Context) Reply(title string, description string) {
+	NewResponse(ctx, false, false).Send(true, title, description)
+}
+
+// ReplyError
+// Send a failure response with a title and description in one call
+// Unlike Send(false, ...), the command usage is not appended, since the error may have nothing to do with the syntax
+func (ctx *Context) ReplyError(title string, description string) {
+	r := NewResponse(ctx, false, false)
+	r.Embed.Title = Truncate(title, maxEmbedTitle)
+	r.Embed.Description = truncateDescription(description)
+	r.SetColor(ctx.Guild.GetFailureColor())
+	r.SendRaw()
+}
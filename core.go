@@ -1,12 +1,11 @@
 package framework
 
 import (
+	"errors"
 	"github.com/bwmarrin/discordgo"
 	tlog "github.com/ubergeek77/tinylog"
 	"os"
 	"os/signal"
-	"strconv"
-	"strings"
 	"syscall"
 )
 
@@ -17,6 +16,55 @@ import (
 // Tells discordgo the amount of messages to cache
 var MessageState = 500
 
+// SetMessageCacheSize
+// Set the amount of messages to cache per channel. 0 disables the message cache
+// This can be called before or after Start; when the bot is running, the new size applies immediately
+func SetMessageCacheSize(n int) error {
+	if n < 0 {
+		return errors.New("message cache size cannot be negative")
+	}
+	MessageState = n
+	for _, shard := range Sessions {
+		shard.State.Lock()
+		shard.State.MaxMessageCount = n
+		shard.State.Unlock()
+	}
+	return nil
+}
+
+// StateCacheStats
+// The number of objects in the discordgo state cache
+type StateCacheStats struct {
+	Guilds   int
+	Channels int
+	Members  int
+	Messages int
+}
+
+// CacheStats
+// Get the number of guilds, channels, members and messages that are currently cached, across every shard
+// The zero value is returned if the bot has not started yet
+func CacheStats() StateCacheStats {
+	var stats StateCacheStats
+	for _, shard := range Sessions {
+		shard.State.RLock()
+		stats.Guilds += len(shard.State.Guilds)
+		for _, guild := range shard.State.Guilds {
+			stats.Members += len(guild.Members)
+			stats.Channels += len(guild.Channels)
+			for _, channel := range guild.Channels {
+				stats.Messages += len(channel.Messages)
+			}
+		}
+		stats.Channels += len(shard.State.PrivateChannels)
+		for _, channel := range shard.State.PrivateChannels {
+			stats.Messages += len(channel.Messages)
+		}
+		shard.State.RUnlock()
+	}
+	return stats
+}
+
 // log
 // The logger for the core bot
 var log = tlog.NewTaggedLogger("BotCore", tlog.NewColor("38;5;111"))
@@ -53,9 +101,18 @@ var ColorSuccess = 0x55F485
 // The color to use for response embeds reporting failure
 var ColorFailure = 0xF45555
 
-// BotPresence
-// Presence data to send when the bot is logging in
-var botPresence discordgo.GatewayStatusUpdate
+// botIntents
+// The gateway intents the bot identifies with
+var botIntents = discordgo.IntentsAllWithoutPrivileged | discordgo.IntentMessageContent
+
+// SetIntents
+// Sets the gateway intents the bot identifies with. This must be called before Start
+// Defaults to every unprivileged intent plus message content, which prefix commands need
+// Slash-only bots can drop discordgo.IntentMessageContent, and welcome messages and auto roles need discordgo.IntentGuildMembers
+// Only request the privileged intents the bot needs, since they have to be approved for verified bots
+func SetIntents(intents discordgo.Intent) {
+	botIntents = intents
+}
 
 // initProvider
 // Stores and allows for the calling of the chosen GuildProvider
@@ -68,13 +125,6 @@ func SetInitProvider(provider func() GuildProvider) {
 	return
 }
 
-// SetPresence
-// Sets the gateway field for bot presence
-func SetPresence(presence discordgo.GatewayStatusUpdate) {
-	botPresence = presence
-	return
-}
-
 // AddAdmin
 // A function that allows admins to be added, but not removed
 func AddAdmin(userId string) {
@@ -103,10 +153,8 @@ func IsAdmin(userId string) bool {
 // IsCommand
 // Check if a given string is a command registered to the core bot
 func IsCommand(trigger string) bool {
-	if _, ok := commands[strings.ToLower(trigger)]; ok {
-		return true
-	}
-	return false
+	_, ok := getCommand(trigger)
+	return ok
 }
 
 // Start the bot.
@@ -118,7 +166,18 @@ func Start() {
 		log.Fatalf("You have not chosen a database provider. Please refer to the docs")
 	}
 	currentProvider = initProvider()
+	if currentProvider.Save == nil || currentProvider.Load == nil {
+		log.Fatalf("The database provider must provide both Save and Load functions")
+	}
+	guildsLock.Lock()
 	Guilds = loadGuilds()
+	guildsLock.Unlock()
+
+	// Load the message catalogs used to translate responses
+	loadCatalogs()
+
+	// Start the health server early, so liveness can be checked while the bot connects
+	startHealthServer()
 
 	// We need a token
 	if botToken == "" {
@@ -132,18 +191,14 @@ func Start() {
 	if err != nil {
 		log.Fatalf("Failed to create Discord session: %s", err)
 	}
-	// Setup State specific variables
-	Session.State.MaxMessageCount = MessageState
-	Session.LogLevel = discordgo.LogWarning
-	Session.SyncEvents = false
-	Session.Identify.Intents = discordgo.IntentsAllWithoutPrivileged | discordgo.IntentMessageContent
-
-	// Set the bots status
-	Session.Identify.Presence = botPresence
+	// Create a session for every shard, with the original session as shard 0
+	if err = createShards(); err != nil {
+		log.Fatalf("Failed to create Discord shards: %s", err)
+	}
 
-	// Open the session
+	// Open the sessions
 	log.Info("Connecting to Discord...")
-	err = Session.Open()
+	err = openShards()
 	if err != nil {
 		log.Fatalf("Failed to connect to Discord: %s", err)
 	}
@@ -151,9 +206,20 @@ func Start() {
 	// Add the commandHandler to the list of user-defined handlers
 	AddDGOHandler(commandHandler)
 
+	// Add the message filter to the list of user-defined handlers
+	AddDGOHandler(filterHandler)
+
 	// Add the slash command handler to the list of user-defined handlers
 	AddDGOHandler(handleInteraction)
 
+	// Add the guild join and leave handlers to the list of user-defined handlers
+	trackStartupGuilds()
+	AddDGOHandler(guildCreateHandler)
+	AddDGOHandler(guildDeleteHandler)
+
+	// Add the welcome message and auto role handler to the list of user-defined handlers
+	AddDGOHandler(guildMemberAddHandler)
+
 	// Add the handlers to the session
 	addDGoHandlers()
 
@@ -163,6 +229,9 @@ func Start() {
 	// Start workers
 	startWorkers()
 
+	// Lift or re-schedule any temporary bans that were stored before the last shutdown
+	restoreTempBans()
+
 	// Print information about the current bot admins
 	numAdmins := 0
 	for userId := range botAdmins {
@@ -200,8 +269,11 @@ func Start() {
 
 	log.Info("Received TERM signal, terminating gracefully.")
 
-	// Set the global loop variable to false so all background loops terminate
-	continueLoop = false
+	// The bot is going away, so stop reporting its health
+	stopHealthServer()
+
+	// Cancel the worker context so all background loops terminate
+	StopWorkers()
 
 	// Make a second sig channel that will respond to user term signal immediately
 	sigInstant := make(chan os.Signal, 1)
@@ -214,10 +286,13 @@ func Start() {
 			// Try locking the worker mutex. This will block if the mutex is already locked
 			// If we are able to lock it, then it means the worker has stopped.
 			lock.Lock()
-			log.Info("Stopped worker " + strconv.Itoa(i))
+			log.Info("Stopped worker " + workerName(workers[i], i))
 			lock.Unlock()
 		}
 
+		// Workers that were run manually may still be running
+		asyncWorkerRuns.Wait()
+
 		log.Info("All routines exited gracefully.")
 
 		// Send our own signal to the instant sig channel
@@ -227,8 +302,13 @@ func Start() {
 	// Keep the thread blocked until the above goroutine finishes closing all workers, or until another TERM is received
 	<-sigInstant
 
+	// Write any guild changes that are still waiting to be saved
+	if err := Flush(); err != nil {
+		log.Errorf("Failed to save all guild data: %s", err)
+	}
+
 	log.Info("Closing the Discord session...")
-	closeErr := Session.Close()
+	closeErr := closeShards()
 	if closeErr != nil {
 		log.Errorf("An error occurred when closing the Discord session: %s", err)
 		return
@@ -0,0 +1,137 @@
+package framework
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// customcommands.go
+// This file contains custom commands, which are simple text responses that guilds can add themselves
+
+// CustomCommand
+// A text response stored in a guild, which is sent when its trigger is used
+// The content can contain the placeholders {user}, {args} and {count}
+type CustomCommand struct {
+	Content     string `json:"content"`
+	InvokeCount int64  `json:"invoke_count"`
+	Public      bool   `json:"public"`
+}
+
+// maxCustomCommandContent
+// The maximum length of a custom command's content, which is the maximum length of a message
+const maxCustomCommandContent = 2000
+
+// AddCustomCommand
+// Add a custom command to this guild, or replace the content of an existing one, then save the guild data
+// Custom commands can't use the trigger or alias of a built-in command
+func (g *Guild) AddCustomCommand(trigger string, content string, public bool) error {
+	trigger = strings.ToLower(strings.TrimSpace(trigger))
+	if trigger == "" || strings.ContainsAny(trigger, " \n\t") {
+		return errors.New("custom command triggers must be a single word")
+	}
+	if _, ok := getCommandByAlias(trigger); ok || IsCommand(trigger) {
+		return errors.New("a built-in command already uses this trigger")
+	}
+	if content == "" {
+		return errors.New("custom command content cannot be blank")
+	}
+	if len([]rune(content)) > maxCustomCommandContent {
+		return errors.New("custom command content cannot be longer than " + strconv.Itoa(maxCustomCommandContent) + " characters")
+	}
+
+	g.lock.Lock()
+	if g.Info.CustomCommands == nil {
+		g.Info.CustomCommands = make(map[string]CustomCommand)
+	}
+	// Keep the invoke count when the content is replaced
+	cc := g.Info.CustomCommands[trigger]
+	cc.Content = content
+	cc.Public = public
+	g.Info.CustomCommands[trigger] = cc
+	g.lock.Unlock()
+
+	return g.save()
+}
+
+// RemoveCustomCommand
+// Remove a custom command from this guild, then save the guild data
+func (g *Guild) RemoveCustomCommand(trigger string) error {
+	trigger = strings.ToLower(trigger)
+	if !g.IsCustomCommand(trigger) {
+		return errors.New("custom command does not exist; nothing to remove")
+	}
+
+	g.lock.Lock()
+	delete(g.Info.CustomCommands, trigger)
+	g.lock.Unlock()
+
+	return g.save()
+}
+
+// IsCustomCommand
+// Check if this guild has a custom command with the given trigger
+func (g *Guild) IsCustomCommand(trigger string) bool {
+	_, ok := g.GetCustomCommand(trigger)
+	return ok
+}
+
+// GetCustomCommand
+// Get a copy of a custom command of this guild
+func (g *Guild) GetCustomCommand(trigger string) (CustomCommand, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	cc, ok := g.Info.CustomCommands[strings.ToLower(trigger)]
+	return cc, ok
+}
+
+// formatCustomCommand
+// Fill in the placeholders of a custom command's content
+func formatCustomCommand(content string, user *discordgo.User, args string, count int64) string {
+	return Truncate(strings.NewReplacer(
+		"{user}", user.Mention(),
+		"{args}", args,
+		"{count}", strconv.FormatInt(count, 10),
+	).Replace(content), maxCustomCommandContent)
+}
+
+// runCustomCommand
+// Run the custom command with the given trigger, if the guild has one
+// Returns false if there is no such custom command, so the caller can fall back to other handling
+func runCustomCommand(g *Guild, message *discordgo.Message, trigger string, args string) bool {
+	cc, ok := g.GetCustomCommand(trigger)
+	if !ok {
+		return false
+	}
+	if !cc.Public && !IsAdmin(message.Author.ID) && !g.IsMod(message.Author.ID) {
+		// The command exists, but this user isn't allowed to use it
+		return true
+	}
+
+	// Re-read the command while incrementing, so concurrent invocations are all counted
+	g.lock.Lock()
+	cc, ok = g.Info.CustomCommands[trigger]
+	if !ok {
+		// The command was removed in the meantime
+		g.lock.Unlock()
+		return true
+	}
+	cc.InvokeCount++
+	g.Info.CustomCommands[trigger] = cc
+	g.lock.Unlock()
+
+	if err := g.save(); err != nil {
+		log.Errorf("Failed to save the invoke count of custom command %s: %s", trigger, err)
+	}
+
+	_, err := Session.ChannelMessageSendComplex(message.ChannelID, &discordgo.MessageSend{
+		Content:         formatCustomCommand(cc.Content, message.Author, args, cc.InvokeCount),
+		AllowedMentions: contentMentions(),
+	})
+	if err != nil {
+		SendErrorReport(g.ID, message.ChannelID, message.Author.ID, "Failed to send custom command "+trigger, err)
+	}
+	return true
+}
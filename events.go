@@ -0,0 +1,62 @@
+package framework
+
+import "sync"
+
+// events.go
+// This file contains a small event bus, so commands and modules can react to each other without depending on each other
+
+// EventHandler
+// A function that is called with the payload of every event it subscribed to
+type EventHandler func(payload interface{})
+
+// eventHandlers
+// A map of event names to the handlers subscribed to them, in the order they subscribed
+var eventHandlers = make(map[string][]EventHandler)
+
+// eventHandlersLock
+// Guards eventHandlers, since handlers may subscribe while events are being published
+var eventHandlersLock sync.RWMutex
+
+// Subscribe
+// Register a handler that is called every time the event is published
+func Subscribe(event string, handler EventHandler) {
+	eventHandlersLock.Lock()
+	defer eventHandlersLock.Unlock()
+	eventHandlers[event] = append(eventHandlers[event], handler)
+}
+
+// Publish
+// Call every handler subscribed to the event with the payload, one after another, and wait for them to finish
+// A handler that panics is reported, and does not stop the remaining handlers from being called
+func Publish(event string, payload interface{}) {
+	eventHandlersLock.RLock()
+	handlers := eventHandlers[event]
+	eventHandlersLock.RUnlock()
+
+	for _, handler := range handlers {
+		callEventHandler(event, handler, payload)
+	}
+}
+
+// PublishAsync
+// Like Publish, but calls every handler in its own goroutine and returns immediately
+func PublishAsync(event string, payload interface{}) {
+	eventHandlersLock.RLock()
+	handlers := eventHandlers[event]
+	eventHandlersLock.RUnlock()
+
+	for _, handler := range handlers {
+		go callEventHandler(event, handler, payload)
+	}
+}
+
+// callEventHandler
+// Call a single event handler, recovering from and reporting any panic
+func callEventHandler(event string, handler EventHandler, payload interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			SendErrorReport("", "", "", "Event handler for \""+event+"\" panicked", recoveredError(r), panicStack())
+		}
+	}()
+	handler(payload)
+}
@@ -0,0 +1,190 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// export.go
+// This file contains exporting a guild's settings, and importing them into another guild
+
+// Export
+// Serialize this guild's settings to JSON, e.g. to copy them to another guild with ImportConfig
+func (g *Guild) Export() ([]byte, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return json.MarshalIndent(g.Info, "", "    ")
+}
+
+// importFilter
+// Checks IDs from another guild against the roles, channels and members of this guild
+type importFilter struct {
+	g        *Guild
+	roles    map[string]bool
+	channels map[string]bool
+	dropped  []string
+}
+
+// newImportFilter
+// Fetch the roles and channels of the guild, so every ID can be checked without an API call per ID
+func (g *Guild) newImportFilter() (*importFilter, error) {
+	f := &importFilter{
+		g:        g,
+		roles:    make(map[string]bool),
+		channels: make(map[string]bool),
+	}
+
+	roles, err := Session.GuildRoles(g.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		f.roles[role.ID] = true
+	}
+
+	channels, err := Session.GuildChannels(g.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		f.channels[channel.ID] = true
+	}
+	return f, nil
+}
+
+// channel
+// Check if a channel exists in this guild, and record it as dropped if it doesn't
+func (f *importFilter) channel(setting string, id string) bool {
+	if id == "" || f.channels[id] {
+		return true
+	}
+	f.dropped = append(f.dropped, fmt.Sprintf("%s: channel %s", setting, id))
+	return false
+}
+
+// channelList
+// Keep only the channels that exist in this guild
+func (f *importFilter) channelList(setting string, ids []string) []string {
+	var kept []string
+	for _, id := range ids {
+		if f.channel(setting, id) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// roleList
+// Keep only the roles that exist in this guild
+func (f *importFilter) roleList(setting string, ids []string) []string {
+	var kept []string
+	for _, id := range ids {
+		if f.roles[id] {
+			kept = append(kept, id)
+			continue
+		}
+		f.dropped = append(f.dropped, fmt.Sprintf("%s: role %s", setting, id))
+	}
+	return kept
+}
+
+// memberOrRoleList
+// Keep only the roles and members that exist in this guild
+func (f *importFilter) memberOrRoleList(setting string, ids []string) []string {
+	var kept []string
+	for _, id := range ids {
+		if f.roles[id] || f.g.IsMember(id) {
+			kept = append(kept, id)
+			continue
+		}
+		f.dropped = append(f.dropped, fmt.Sprintf("%s: member or role %s", setting, id))
+	}
+	return kept
+}
+
+// ImportConfig
+// Apply settings exported from another guild with Export, then save the guild data
+// Roles, channels and members that don't exist in this guild are dropped instead of being copied,
+// and are returned in a human-readable list, so they can be shown to the user
+// The guild's storage (warnings, temporary bans, etc.) is never imported, since it belongs to the other guild
+func (g *Guild) ImportConfig(data []byte) ([]string, error) {
+	var info GuildInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid guild config: %w", err)
+	}
+	migrateGuildInfo(g.ID, &info)
+	if info.Prefix == "" {
+		return nil, fmt.Errorf("invalid guild config: the prefix is blank")
+	}
+	for name, color := range map[string]int{"success": info.SuccessColor, "failure": info.FailureColor} {
+		if color < 0 || color > 0xFFFFFF {
+			return nil, fmt.Errorf("invalid guild config: the %s color is out of range", name)
+		}
+	}
+	for _, pattern := range info.BlockedPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid guild config: blocked pattern %q: %w", pattern, err)
+		}
+	}
+
+	f, err := g.newImportFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	channelDisabledCommands := make(map[string][]string)
+	for channelId, commands := range info.ChannelDisabledCommands {
+		if f.channel("channel disabled commands", channelId) {
+			channelDisabledCommands[channelId] = commands
+		}
+	}
+	groupResponseChannels := make(map[Group]string)
+	for group, channelId := range info.GroupResponseChannels {
+		if f.channel("group response channel", channelId) {
+			groupResponseChannels[group] = channelId
+		}
+	}
+	responseChannelId := info.ResponseChannelId
+	if !f.channel("response channel", responseChannelId) {
+		responseChannelId = ""
+	}
+	welcomeChannelId := info.WelcomeChannelId
+	if !f.channel("welcome channel", welcomeChannelId) {
+		welcomeChannelId = ""
+	}
+	autoRoleIds := f.roleList("auto roles", info.AutoRoleIds)
+	ignoredChannels := f.channelList("ignored channels", info.IgnoredChannels)
+	whitelistedChannels := f.channelList("whitelisted channels", info.WhitelistedChannels)
+	ignoredIds := f.memberOrRoleList("ignored", info.IgnoredIds)
+	moderatorIds := f.memberOrRoleList("moderators", info.ModeratorIds)
+	whitelistIds := f.memberOrRoleList("whitelist", info.WhitelistIds)
+
+	g.lock.Lock()
+	g.Info.AutoRoleIds = autoRoleIds
+	g.Info.BlockedPatterns = info.BlockedPatterns
+	g.Info.ChannelDisabledCommands = channelDisabledCommands
+	g.Info.CustomCommands = info.CustomCommands
+	g.Info.DeletePolicy = info.DeletePolicy
+	g.Info.FailureColor = info.FailureColor
+	g.Info.GlobalDisabledCommands = info.GlobalDisabledCommands
+	g.Info.GroupResponseChannels = groupResponseChannels
+	g.Info.IgnoredChannels = ignoredChannels
+	g.Info.IgnoredIds = ignoredIds
+	g.Info.Locale = info.Locale
+	g.Info.ModeratorIds = moderatorIds
+	g.Info.Prefix = info.Prefix
+	g.Info.ResponseChannelId = responseChannelId
+	g.Info.SuccessColor = info.SuccessColor
+	g.Info.SuggestCommands = info.SuggestCommands
+	g.Info.WarnOnFilter = info.WarnOnFilter
+	g.Info.WelcomeChannelId = welcomeChannelId
+	g.Info.WelcomeMessage = info.WelcomeMessage
+	g.Info.WhitelistedChannels = whitelistedChannels
+	g.Info.WhitelistIds = whitelistIds
+	g.lock.Unlock()
+
+	// The blocked patterns have changed
+	g.invalidatePatterns()
+	return f.dropped, g.save()
+}
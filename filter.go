@@ -0,0 +1,165 @@
+package framework
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// filter.go
+// This file contains the opt-in auto-moderation filter, which deletes messages matching a guild's blocked patterns
+
+// filterCache
+// A map of guild IDs to their compiled blocked patterns
+// Patterns are compiled once and cached here, so they aren't recompiled on every message
+var filterCache = make(map[string][]*regexp.Regexp)
+
+// filterCacheLock
+// Guards filterCache, since message handlers run concurrently
+var filterCacheLock sync.Mutex
+
+// compiledPatterns
+// Return the compiled blocked patterns for this guild, compiling and caching them if necessary
+// Patterns that fail to compile are skipped
+func (g *Guild) compiledPatterns() []*regexp.Regexp {
+	filterCacheLock.Lock()
+	defer filterCacheLock.Unlock()
+
+	if patterns, ok := filterCache[g.ID]; ok {
+		return patterns
+	}
+
+	var patterns []*regexp.Regexp
+	for _, pattern := range g.Info.BlockedPatterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Errorf("Failed to compile blocked pattern \"%s\" for guild %s: %s", pattern, g.ID, err)
+			continue
+		}
+		patterns = append(patterns, compiled)
+	}
+
+	filterCache[g.ID] = patterns
+	return patterns
+}
+
+// invalidatePatterns
+// Drop this guild's cached patterns, so they are recompiled the next time they are needed
+func (g *Guild) invalidatePatterns() {
+	filterCacheLock.Lock()
+	delete(filterCache, g.ID)
+	filterCacheLock.Unlock()
+}
+
+// IsBlockedPattern
+// Check if a given pattern is already on the list of blocked patterns
+func (g *Guild) IsBlockedPattern(pattern string) bool {
+	for _, blocked := range g.Info.BlockedPatterns {
+		if blocked == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddBlockedPattern
+// Add a regex pattern to the list of blocked patterns, then save the guild data
+// The pattern must be a valid regular expression
+func (g *Guild) AddBlockedPattern(pattern string) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return errors.New("provided pattern is not a valid regular expression")
+	}
+
+	if g.IsBlockedPattern(pattern) {
+		return errors.New("pattern is already blocked; nothing to add")
+	}
+
+	err := g.update(func() {
+		g.Info.BlockedPatterns = append(g.Info.BlockedPatterns, pattern)
+	})
+	g.invalidatePatterns()
+	return err
+}
+
+// RemoveBlockedPattern
+// Remove a regex pattern from the list of blocked patterns, then save the guild data
+func (g *Guild) RemoveBlockedPattern(pattern string) error {
+	if !g.IsBlockedPattern(pattern) {
+		return errors.New("pattern is not blocked; nothing to remove")
+	}
+
+	err := g.update(func() {
+		g.Info.BlockedPatterns = RemoveItem(g.Info.BlockedPatterns, pattern)
+	})
+	g.invalidatePatterns()
+	return err
+}
+
+// SetWarnOnFilter
+// Set whether members are warned when the filter deletes one of their messages, then save the guild data
+func (g *Guild) SetWarnOnFilter(warn bool) error {
+	return g.update(func() {
+		g.Info.WarnOnFilter = warn
+	})
+}
+
+// MatchesBlockedPattern
+// Determine if the given content matches any of this guild's blocked patterns
+func (g *Guild) MatchesBlockedPattern(content string) bool {
+	for _, pattern := range g.compiledPatterns() {
+		if pattern.MatchString(content) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterHandler
+// This handler will be added to a *discordgo.Session, and will delete incoming messages that match a blocked pattern
+func filterHandler(session *discordgo.Session, message *discordgo.MessageCreate) {
+	// Ignore messages sent by the bot, and messages sent outside a guild
+	if message.Author == nil || message.Author.ID == session.State.User.ID || message.GuildID == "" {
+		return
+	}
+
+	g := getGuild(message.GuildID)
+
+	// The filter is opt-in; nothing to do if no patterns are configured
+	if len(g.Info.BlockedPatterns) == 0 {
+		return
+	}
+
+	// Commands are handled by the commandHandler, so don't filter them
+	if trigger, _ := ExtractCommand(&g.Info, message.Content); trigger != nil {
+		if _, ok := getCommandByAlias(*trigger); ok {
+			return
+		}
+	}
+
+	if !g.MatchesBlockedPattern(message.Content) {
+		return
+	}
+
+	// Bot admins and moderators are exempt from the filter
+	// These are checked last, since IsMod may require an API call
+	if IsAdmin(message.Author.ID) || g.IsMod(message.Author.ID) {
+		return
+	}
+
+	err := session.ChannelMessageDelete(message.ChannelID, message.ID)
+	if err != nil {
+		SendErrorReport(message.GuildID, message.ChannelID, message.Author.ID, "Failed to delete filtered message: "+message.ID, err)
+		return
+	}
+
+	if g.Info.WarnOnFilter {
+		_, err = g.AddWarning(message.Author.ID, session.State.User.ID, "Message matched a blocked pattern")
+		if err != nil {
+			SendErrorReport(message.GuildID, message.ChannelID, message.Author.ID, "Failed to warn filtered member", err)
+		}
+	}
+}
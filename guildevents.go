@@ -0,0 +1,92 @@
+package framework
+
+import (
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// guildevents.go
+// This file contains the handlers that react to the bot joining or leaving a guild
+
+// OnGuildJoin
+// An optional hook that is called when the bot is added to a guild, e.g. to send a welcome message
+// The guild's data has already been created by the time this is called
+var OnGuildJoin func(g *Guild)
+
+// OnGuildLeave
+// An optional hook that is called when the bot is removed from a guild, after the guild's data has been deleted
+var OnGuildLeave func(guildId string)
+
+// unavailableGuilds
+// Guilds that Discord will send a GuildCreate for without the bot having joined them
+// This is the case for every guild on startup, and for guilds coming back from an outage
+var unavailableGuilds = make(map[string]bool)
+
+// unavailableGuildsLock
+// Guards unavailableGuilds, since events are handled concurrently
+var unavailableGuildsLock sync.Mutex
+
+// trackStartupGuilds
+// Mark the guilds the bot was already in when it connected, so their GuildCreate isn't mistaken for a join
+func trackStartupGuilds() {
+	unavailableGuildsLock.Lock()
+	defer unavailableGuildsLock.Unlock()
+
+	for _, shard := range Sessions {
+		shard.State.RLock()
+		for _, guild := range shard.State.Guilds {
+			unavailableGuilds[guild.ID] = true
+		}
+		shard.State.RUnlock()
+	}
+}
+
+// guildCreateHandler
+// Detect when the bot joins a guild
+func guildCreateHandler(session *discordgo.Session, event *discordgo.GuildCreate) {
+	unavailableGuildsLock.Lock()
+	wasUnavailable := unavailableGuilds[event.ID]
+	delete(unavailableGuilds, event.ID)
+	unavailableGuildsLock.Unlock()
+
+	// The bot was already in this guild; it just became available
+	if wasUnavailable {
+		return
+	}
+
+	// Make sure the guild's data exists before anything else uses it
+	g := getGuild(event.ID)
+	log.Infof("Joined guild %s (%s)", event.Name, event.ID)
+
+	if OnGuildJoin != nil {
+		OnGuildJoin(g)
+	}
+}
+
+// guildDeleteHandler
+// Detect when the bot is removed from a guild, and delete its data
+func guildDeleteHandler(session *discordgo.Session, event *discordgo.GuildDelete) {
+	// A guild outage also sends a GuildDelete, but the bot is still in the guild
+	if event.Unavailable {
+		unavailableGuildsLock.Lock()
+		unavailableGuilds[event.ID] = true
+		unavailableGuildsLock.Unlock()
+		return
+	}
+
+	guildsLock.Lock()
+	g, ok := Guilds[event.ID]
+	delete(Guilds, event.ID)
+	guildsLock.Unlock()
+
+	if ok {
+		g.invalidatePatterns()
+	}
+	deleteGuildData(event.ID)
+	log.Infof("Left guild %s", event.ID)
+
+	if OnGuildLeave != nil {
+		OnGuildLeave(event.ID)
+	}
+}
@@ -1,8 +1,13 @@
 package framework
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -14,26 +19,42 @@ import (
 // GuildInfo
 // This is all the settings and data that needs to be stored about a single guild
 type GuildInfo struct {
-	AddedDate               int64                  `json:"added_date"`
-	ChannelDisabledCommands map[string][]string    `json:"channel_disabled_commands"`
-	DeletePolicy            bool                   `json:"delete_policy"`
-	GlobalDisabledCommands  []string               `json:"global_disabled_commands"`
-	IgnoredChannels         []string               `json:"ignored_channels"`
-	IgnoredIds              []string               `json:"ignored_ids"`
-	ModeratorIds            []string               `json:"moderator_ids"`
-	Prefix                  string                 `json:"prefix,"`
-	ResponseChannelId       string                 `json:"response_channel_id"`
-	Storage                 map[string]interface{} `json:"storage"`
-	WhitelistedChannels     []string               `json:"whitelisted_channels"`
-	WhitelistIds            []string               `json:"whitelist_ids"`
+	AddedDate               int64                    `json:"added_date"`
+	AutoRoleIds             []string                 `json:"auto_role_ids"`
+	BlockedPatterns         []string                 `json:"blocked_patterns"`
+	ChannelDisabledCommands map[string][]string      `json:"channel_disabled_commands"`
+	CustomCommands          map[string]CustomCommand `json:"custom_commands"`
+	DeletePolicy            bool                     `json:"delete_policy"`
+	FailureColor            int                      `json:"failure_color"`
+	GlobalDisabledCommands  []string                 `json:"global_disabled_commands"`
+	GroupResponseChannels   map[Group]string         `json:"group_response_channels"`
+	IgnoredChannels         []string                 `json:"ignored_channels"`
+	IgnoredIds              []string                 `json:"ignored_ids"`
+	Locale                  string                   `json:"locale"`
+	ModeratorIds            []string                 `json:"moderator_ids"`
+	Prefix                  string                   `json:"prefix,"`
+	ResponseChannelId       string                   `json:"response_channel_id"`
+	SchemaVersion           int                      `json:"schema_version"`
+	Storage                 map[string]interface{}   `json:"storage"`
+	SuccessColor            int                      `json:"success_color"`
+	SuggestCommands         bool                     `json:"suggest_commands"`
+	WarnOnFilter            bool                     `json:"warn_on_filter"`
+	WelcomeChannelId        string                   `json:"welcome_channel_id"`
+	WelcomeMessage          string                   `json:"welcome_message"`
+	WhitelistedChannels     []string                 `json:"whitelisted_channels"`
+	WhitelistIds            []string                 `json:"whitelist_ids"`
 }
 
 //GuildProvider
 // Type that holds functions that can be easily modified to support a wide range
 // of storage types
 type GuildProvider struct {
-	Save func(guild *Guild)
+	Save func(guild *Guild) error
 	Load func() map[string]*Guild
+	// LoadOne is optional, for providers that can fetch a single guild without loading all of them
+	LoadOne func(guildId string) (*GuildInfo, error)
+	// Delete is optional, and removes a guild's data when the bot leaves it
+	Delete func(guildId string)
 }
 
 // Guild
@@ -41,6 +62,9 @@ type GuildProvider struct {
 type Guild struct {
 	ID   string
 	Info GuildInfo
+
+	// lock is held while the guild is saved or copied, and while its data is changed
+	lock sync.Mutex
 }
 
 // Guilds
@@ -49,11 +73,92 @@ type Guild struct {
 // Otherwise, there will be information desync
 var Guilds = make(map[string]*Guild)
 
+// DefaultConfig
+// The settings new guilds start with, instead of every guild starting empty
+type DefaultConfig struct {
+	Prefix                 string   `json:"prefix"`
+	GlobalDisabledCommands []string `json:"global_disabled_commands"`
+	ModeratorIds           []string `json:"moderator_ids"`
+}
+
+// defaultConfig
+// The settings new guilds start with
+var defaultConfig = DefaultConfig{
+	Prefix: "!",
+}
+
+// SetDefaultConfig
+// Set the settings new guilds start with. Guilds that already exist are not changed
+// A blank prefix keeps the default prefix of "!"
+func SetDefaultConfig(config DefaultConfig) {
+	if config.Prefix == "" {
+		config.Prefix = "!"
+	}
+	defaultConfig = config
+}
+
+// LoadDefaultConfig
+// Read the settings new guilds start with from a JSON file, see DefaultConfig for the fields
+func LoadDefaultConfig(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var config DefaultConfig
+	if err = json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse default config %s: %w", file, err)
+	}
+
+	SetDefaultConfig(config)
+	return nil
+}
+
+// newGuildInfo
+// Create the info of a new guild, seeded from the default config
+func newGuildInfo() GuildInfo {
+	return GuildInfo{
+		AddedDate:               time.Now().Unix(),
+		AutoRoleIds:             nil,
+		BlockedPatterns:         nil,
+		ChannelDisabledCommands: nil,
+		CustomCommands:          nil,
+		DeletePolicy:            false,
+		FailureColor:            0,
+		GlobalDisabledCommands:  append([]string(nil), defaultConfig.GlobalDisabledCommands...),
+		GroupResponseChannels:   nil,
+		IgnoredChannels:         nil,
+		IgnoredIds:              nil,
+		Locale:                  "",
+		ModeratorIds:            append([]string(nil), defaultConfig.ModeratorIds...),
+		Prefix:                  defaultConfig.Prefix,
+		ResponseChannelId:       "",
+		SchemaVersion:           latestSchemaVersion(),
+		Storage:                 make(map[string]interface{}),
+		SuccessColor:            0,
+		SuggestCommands:         false,
+		WarnOnFilter:            false,
+		WelcomeChannelId:        "",
+		WelcomeMessage:          "",
+		WhitelistedChannels:     nil,
+		WhitelistIds:            nil,
+	}
+}
+
+// guildsLock
+// Guards Guilds, since events (and therefore getGuild) are handled concurrently
+var guildsLock sync.RWMutex
+
 // currentProvider
 // A reference to a struct of functions that provides the guild info system with a database
 // Or similar system to save guild data.
+// Every load and save of guild data goes through it; it is set from the init provider when the bot starts
 var currentProvider GuildProvider
 
+// errNoProvider
+// Returned when guild data is saved before a provider with a Save function has been set up
+var errNoProvider = errors.New("no guild provider has been set up; call SetInitProvider before Start")
+
 // getGuild
 // Return a Guild object corresponding to the given guildId
 // If the guild doesn't exist, initialize a new guild and save it before returning
@@ -61,51 +166,39 @@ var currentProvider GuildProvider
 func getGuild(guildId string) *Guild {
 	// The command is being ran as a dm, send back an empty guild object with default fields
 	if guildId == "" {
+		info := newGuildInfo()
+		// Default moderators are for guilds; they shouldn't get extra permissions in DMs
+		info.ModeratorIds = nil
 		return &Guild{
-			ID: "",
-			Info: GuildInfo{
-				AddedDate:               time.Now().Unix(),
-				ChannelDisabledCommands: nil,
-				DeletePolicy:            false,
-				GlobalDisabledCommands:  nil,
-				IgnoredChannels:         nil,
-				IgnoredIds:              nil,
-				ModeratorIds:            nil,
-				Prefix:                  "!",
-				ResponseChannelId:       "",
-				Storage:                 make(map[string]interface{}),
-				WhitelistedChannels:     nil,
-				WhitelistIds:            nil,
-			},
-		}
-	}
-	if guild, ok := Guilds[guildId]; ok {
+			ID:   "",
+			Info: info,
+		}
+	}
+	guildsLock.RLock()
+	guild, ok := Guilds[guildId]
+	guildsLock.RUnlock()
+	if ok {
+		return guild
+	}
+
+	guildsLock.Lock()
+	// Another handler may have created the guild while the lock was released
+	if guild, ok = Guilds[guildId]; ok {
+		guildsLock.Unlock()
 		return guild
 	} else {
 		// Create a new guild with default values
 		newGuild := Guild{
-			ID: guildId,
-			Info: GuildInfo{
-				AddedDate:               time.Now().Unix(),
-				ChannelDisabledCommands: nil,
-				DeletePolicy:            false,
-				GlobalDisabledCommands:  nil,
-				IgnoredChannels:         nil,
-				IgnoredIds:              nil,
-				ModeratorIds:            nil,
-				Prefix:                  "!",
-				ResponseChannelId:       "",
-				Storage:                 make(map[string]interface{}),
-				WhitelistedChannels:     nil,
-				WhitelistIds:            nil,
-			},
+			ID:   guildId,
+			Info: newGuildInfo(),
 		}
 		// Add the new guild to the map of guilds
 		Guilds[guildId] = &newGuild
+		guildsLock.Unlock()
 
 		// Save the guild to database
-		// A failed save is fatal, so we can count on this being successful
-		newGuild.save()
+		// A failed save is reported, and the guild is still usable from memory
+		_ = newGuild.save()
 
 		// Log that a new guild was detected
 		log.Infof("New guild detected: %s", guildId)
@@ -114,16 +207,260 @@ func getGuild(guildId string) *Guild {
 	}
 }
 
+// GetGuild
+// Get a guild by its ID, creating it with default values if it isn't known yet
+// Unlike the Guild passed in a Context, this can be used to act on any guild, e.g. from a worker
+func GetGuild(guildId string) (*Guild, error) {
+	// Only accept a bare ID, since a blank or mangled ID would create a bogus guild
+	if cleanedId := CleanId(guildId); cleanedId == "" || cleanedId != guildId {
+		return nil, errors.New("provided guild ID is invalid")
+	}
+
+	return getGuild(guildId), nil
+}
+
+// AllGuilds
+// Get a snapshot of all known guilds, which is safe to iterate over while guilds are being added
+func AllGuilds() []*Guild {
+	guildsLock.RLock()
+	defer guildsLock.RUnlock()
+
+	guilds := make([]*Guild, 0, len(Guilds))
+	for _, g := range Guilds {
+		guilds = append(guilds, g)
+	}
+	return guilds
+}
+
 // loadGuilds
 // Load all known guilds from the database
+// Guilds saved with an older schema version are migrated, and saved again
 func loadGuilds() map[string]*Guild {
-	return currentProvider.Load()
+	guilds := currentProvider.Load()
+	migrateGuilds(guilds)
+	return guilds
+}
+
+// loadGuild
+// Load the data of a single guild from the provider
+// If the provider can't load a single guild, all guilds are loaded and the requested one is picked out
+// The data is migrated to the latest schema version, but not saved
+func loadGuild(guildId string) (*GuildInfo, error) {
+	var info *GuildInfo
+	if currentProvider.LoadOne != nil {
+		var err error
+		if info, err = currentProvider.LoadOne(guildId); err != nil {
+			return nil, err
+		}
+	} else if guild, ok := currentProvider.Load()[guildId]; ok {
+		info = &guild.Info
+	} else {
+		return nil, errors.New("guild " + guildId + " was not found in the provider")
+	}
+
+	migrateGuildInfo(guildId, info)
+	return info, nil
+}
+
+// Reload
+// Re-read this guild's data from the provider, e.g. after it was edited outside the bot
+// The data is replaced in place, so existing pointers to this guild stay valid
+func (g *Guild) Reload() error {
+	info, err := loadGuild(g.ID)
+	if err != nil {
+		return err
+	}
+
+	g.lock.Lock()
+	g.Info = *info
+	g.lock.Unlock()
+
+	// The blocked patterns may have changed
+	g.invalidatePatterns()
+	return nil
 }
 
 // save
 // saves guild data to the database
-func (g *Guild) save() {
-	currentProvider.Save(g)
+// Failures are reported to the bot admins, and returned so the caller can tell the user the change wasn't saved
+func (g *Guild) save() error {
+	if g.queueSave() {
+		return nil
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.saveLocked()
+}
+
+// saveLocked
+// Save the guild data through the provider while g.lock is already held
+// Failures are reported to the bot admins, and returned
+func (g *Guild) saveLocked() error {
+	err := errNoProvider
+	if currentProvider.Save != nil {
+		err = currentProvider.Save(g)
+	}
+	if err != nil {
+		SendErrorReport(g.ID, "", "", "Failed to save guild data", err)
+	}
+	return err
+}
+
+// commitLocked
+// Save the guild data after a change made while g.lock is held, or queue the save if saves are debounced
+func (g *Guild) commitLocked() error {
+	if g.queueSave() {
+		return nil
+	}
+	return g.saveLocked()
+}
+
+// update
+// Make a change to the guild data while holding g.lock, then save it (or queue the save if saves are debounced)
+// Setters go through this, so a debounced save never reads the guild data while it is being changed
+func (g *Guild) update(change func()) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	change()
+	return g.commitLocked()
+}
+
+// -- Debounced Saving --
+
+// saveImmediately
+// Whether guild data is saved on every change, or changes are collected and saved together after saveDelay
+var saveImmediately = true
+
+// saveDelay
+// How long changes are collected before they are saved, when saves are debounced
+var saveDelay = time.Second
+
+// dirtyGuilds
+// The guilds with changes that haven't been saved yet, when saves are debounced
+var dirtyGuilds = make(map[string]*Guild)
+
+// dirtyGuildsLock
+// Guards dirtyGuilds and flushTimer
+var dirtyGuildsLock sync.Mutex
+
+// flushTimer
+// The timer that saves the dirty guilds, if one is pending
+var flushTimer *time.Timer
+
+// flushLock
+// Makes sure flushes happen one at a time, so an older snapshot of a guild is never written over a newer one
+var flushLock sync.Mutex
+
+// SetSaveMode
+// Set whether guild data is saved on every change (the default), or debounced
+// When debounced, a guild that changes several times within the delay is only written once, which reduces writes
+// The optional delay sets how long changes are collected before they are saved; it defaults to one second
+// Save errors are still reported to the bot admins when debounced, but the setters can't return them
+func SetSaveMode(immediate bool, delay ...time.Duration) {
+	dirtyGuildsLock.Lock()
+	saveImmediately = immediate
+	if len(delay) > 0 && delay[0] > 0 {
+		saveDelay = delay[0]
+	}
+	dirtyGuildsLock.Unlock()
+
+	// Don't leave changes behind when switching back to immediate saves
+	if immediate {
+		_ = Flush()
+	}
+}
+
+// queueSave
+// Mark the guild as changed, if saves are debounced, and make sure a flush is scheduled
+// Returns false if saves are immediate, in which case the caller has to save the guild itself
+func (g *Guild) queueSave() bool {
+	dirtyGuildsLock.Lock()
+	defer dirtyGuildsLock.Unlock()
+	if saveImmediately {
+		return false
+	}
+
+	dirtyGuilds[g.ID] = g
+	if flushTimer == nil {
+		flushTimer = time.AfterFunc(saveDelay, func() {
+			_ = Flush()
+		})
+	}
+	return true
+}
+
+// Flush
+// Save every guild with changes that haven't been saved yet, when saves are debounced
+// Returns the first error that occurred; every failure is also reported to the bot admins
+func Flush() error {
+	flushLock.Lock()
+	defer flushLock.Unlock()
+
+	dirtyGuildsLock.Lock()
+	pending := dirtyGuilds
+	dirtyGuilds = make(map[string]*Guild)
+	if flushTimer != nil {
+		flushTimer.Stop()
+		flushTimer = nil
+	}
+	dirtyGuildsLock.Unlock()
+
+	var firstErr error
+	for _, g := range pending {
+		// Don't write the data of a guild the bot has left back after it was deleted
+		guildsLock.RLock()
+		current, known := Guilds[g.ID]
+		guildsLock.RUnlock()
+		if !known || current != g {
+			continue
+		}
+
+		// Save a copy, so the guild can be changed while it is being written
+		snapshot, err := g.snapshot()
+		if err != nil {
+			SendErrorReport(g.ID, "", "", "Failed to save guild data", err)
+		} else {
+			// The copy isn't shared, so there is nothing to lock
+			err = snapshot.saveLocked()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// deleteGuildData
+// Delete the data of a guild that was removed from Guilds, along with any save of it that is still pending
+func deleteGuildData(guildId string) {
+	dirtyGuildsLock.Lock()
+	delete(dirtyGuilds, guildId)
+	dirtyGuildsLock.Unlock()
+
+	// Wait for a flush that may be writing the guild right now, so it can't recreate the data after it is deleted
+	flushLock.Lock()
+	defer flushLock.Unlock()
+	if currentProvider.Delete != nil {
+		currentProvider.Delete(guildId)
+	}
+}
+
+// snapshot
+// Make a deep copy of the guild data while holding g.lock
+func (g *Guild) snapshot() (*Guild, error) {
+	g.lock.Lock()
+	data, err := json.Marshal(g.Info)
+	g.lock.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy the data of guild %s: %w", g.ID, err)
+	}
+
+	copied := &Guild{ID: g.ID}
+	if err = json.Unmarshal(data, &copied.Info); err != nil {
+		return nil, fmt.Errorf("failed to copy the data of guild %s: %w", g.ID, err)
+	}
+	return copied, nil
 }
 
 // GetMember
@@ -236,11 +573,65 @@ func (g *Guild) IsChannel(channelId string) bool {
 	return true
 }
 
+// ResolveIds
+// Turn a list of IDs into human-readable names, e.g. for showing a guild's settings
+// Roles become "@rolename", channels become "#channelname", and members become "@username"
+// IDs that can't be resolved are left as they are
+func (g *Guild) ResolveIds(ids []string) []string {
+	// Roles and channels are fetched at most once, and only if there is an ID to resolve
+	var roles map[string]string
+	var channels map[string]string
+	resolved := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		if roles == nil {
+			roles = make(map[string]string)
+			if guildRoles, err := Session.GuildRoles(g.ID); err == nil {
+				for _, role := range guildRoles {
+					roles[role.ID] = role.Name
+				}
+			}
+		}
+		if name, ok := roles[id]; ok {
+			resolved = append(resolved, "@"+name)
+			continue
+		}
+
+		if channels == nil {
+			channels = make(map[string]string)
+			if guildChannels, err := Session.GuildChannels(g.ID); err == nil {
+				for _, channel := range guildChannels {
+					channels[channel.ID] = channel.Name
+				}
+			}
+		}
+		if name, ok := channels[id]; ok {
+			resolved = append(resolved, "#"+name)
+			continue
+		}
+
+		if member, err := g.GetMember(id); err == nil && member.User != nil {
+			resolved = append(resolved, "@"+member.User.Username)
+			continue
+		}
+
+		resolved = append(resolved, id)
+	}
+
+	return resolved
+}
+
 // MemberOrRoleInList
 // This is a higher-level function specifically for the Moderator, Ignored, and Whitelist checks
 // Check if a given ID - member or role - exists in a given list, while automatically checking member roles if necessary
 func (g *Guild) MemberOrRoleInList(checkId string, list []string) bool {
+	// An empty list can't contain anything, so skip the API calls
+	if len(list) == 0 {
+		return false
+	}
+
 	// Check if the ID represents a member
+	// Members and roles are told apart by the member lookup, so a member never causes a role lookup
 	member, err := g.GetMember(checkId)
 	if err == nil {
 		// This is a member, check if their ID is found in the list directly, OR if a role they have is found in the list
@@ -277,9 +668,63 @@ func (g *Guild) MemberOrRoleInList(checkId string, list []string) bool {
 
 // SetPrefix
 // Set the prefix, then save the guild data
-func (g *Guild) SetPrefix(newPrefix string) {
-	g.Info.Prefix = newPrefix
-	g.save()
+func (g *Guild) SetPrefix(newPrefix string) error {
+	return g.update(func() {
+		g.Info.Prefix = newPrefix
+	})
+}
+
+// SetLocale
+// Set the locale used to translate responses in this guild, e.g. "en-US"
+// A blank locale uses DefaultLocale
+func (g *Guild) SetLocale(locale string) error {
+	return g.update(func() {
+		g.Info.Locale = locale
+	})
+}
+
+// SetSuccessColor
+// Set the color used for response embeds reporting success, then save the guild data
+// A color of 0 uses ColorSuccess
+func (g *Guild) SetSuccessColor(color int) error {
+	if color < 0 || color > 0xFFFFFF {
+		return errors.New("color must be between 0x000000 and 0xFFFFFF")
+	}
+	return g.update(func() {
+		g.Info.SuccessColor = color
+	})
+}
+
+// SetFailureColor
+// Set the color used for response embeds reporting failure, then save the guild data
+// A color of 0 uses ColorFailure
+func (g *Guild) SetFailureColor(color int) error {
+	if color < 0 || color > 0xFFFFFF {
+		return errors.New("color must be between 0x000000 and 0xFFFFFF")
+	}
+	return g.update(func() {
+		g.Info.FailureColor = color
+	})
+}
+
+// GetSuccessColor
+// Get the color this guild uses for response embeds reporting success
+// Falls back to ColorSuccess if the guild hasn't configured one, or if there is no guild
+func (g *Guild) GetSuccessColor() int {
+	if g == nil || g.Info.SuccessColor == 0 {
+		return ColorSuccess
+	}
+	return g.Info.SuccessColor
+}
+
+// GetFailureColor
+// Get the color this guild uses for response embeds reporting failure
+// Falls back to ColorFailure if the guild hasn't configured one, or if there is no guild
+func (g *Guild) GetFailureColor() int {
+	if g == nil || g.Info.FailureColor == 0 {
+		return ColorFailure
+	}
+	return g.Info.FailureColor
 }
 
 // IsMod
@@ -297,9 +742,9 @@ func (g *Guild) AddMod(addId string) error {
 		if g.IsMod(member.User.ID) {
 			return errors.New("member is already a bot moderator in this guild; nothing to add")
 		}
-		g.Info.ModeratorIds = append(g.Info.ModeratorIds, member.User.ID)
-		g.save()
-		return nil
+		return g.update(func() {
+			g.Info.ModeratorIds = append(g.Info.ModeratorIds, member.User.ID)
+		})
 	}
 
 	// Add the ID if it is a role
@@ -308,9 +753,9 @@ func (g *Guild) AddMod(addId string) error {
 		if g.IsMod(role.ID) {
 			return errors.New("role is already a bot moderator in this guild; nothing to add")
 		}
-		g.Info.ModeratorIds = append(g.Info.ModeratorIds, role.ID)
-		g.save()
-		return nil
+		return g.update(func() {
+			g.Info.ModeratorIds = append(g.Info.ModeratorIds, role.ID)
+		})
 	}
 
 	return errors.New("failed to locate member or role")
@@ -328,9 +773,9 @@ func (g *Guild) RemoveMod(remId string) error {
 		return errors.New("id is not a bot moderator in this guild; nothing to remove")
 	}
 
-	g.Info.ModeratorIds = RemoveItem(g.Info.ModeratorIds, cleanedId)
-	g.save()
-	return nil
+	return g.update(func() {
+		g.Info.ModeratorIds = RemoveItem(g.Info.ModeratorIds, cleanedId)
+	})
 }
 
 // MemberOrRoleIsWhitelisted
@@ -358,22 +803,18 @@ func (g *Guild) AddMemberOrRoleToWhitelist(addId string) error {
 		return errors.New("provided ID is invalid")
 	}
 
-	if g.MemberOrRoleIsWhitelisted(cleanedId) {
+	// An empty whitelist allows everyone, so check the list itself rather than MemberOrRoleIsWhitelisted
+	if g.MemberOrRoleInList(cleanedId, g.Info.WhitelistIds) {
 		return errors.New("id is already whitelisted in this guild; nothing to add")
 	}
 
-	g.Info.WhitelistIds = append(g.Info.WhitelistIds, cleanedId)
-	g.save()
-
-	// If this ID is ignored, remove it from the ignore list, as these are mutually exclusive
-	if g.MemberOrRoleIsIgnored(cleanedId) {
-		err := g.RemoveMemberOrRoleFromIgnored(cleanedId)
-		if err != nil {
-			return err
-		}
-	}
+	return g.update(func() {
+		g.Info.WhitelistIds = append(g.Info.WhitelistIds, cleanedId)
 
-	return nil
+		// Take the ID off the ignore list too, as these are mutually exclusive
+		// Both lists are changed before saving, so the ID can't be left on both if the save fails
+		g.Info.IgnoredIds = RemoveItem(g.Info.IgnoredIds, cleanedId)
+	})
 }
 
 // RemoveMemberOrRoleFromWhitelist
@@ -388,9 +829,9 @@ func (g *Guild) RemoveMemberOrRoleFromWhitelist(remId string) error {
 		return errors.New("id is not whitelisted in this guild; nothing to remove")
 	}
 
-	g.Info.WhitelistIds = RemoveItem(g.Info.WhitelistIds, cleanedId)
-	g.save()
-	return nil
+	return g.update(func() {
+		g.Info.WhitelistIds = RemoveItem(g.Info.WhitelistIds, cleanedId)
+	})
 }
 
 // MemberOrRoleIsIgnored
@@ -422,18 +863,13 @@ func (g *Guild) AddMemberOrRoleToIgnored(addId string) error {
 		return errors.New("id is already ignored in this guild; nothing to add")
 	}
 
-	g.Info.IgnoredIds = append(g.Info.IgnoredIds, cleanedId)
-	g.save()
+	return g.update(func() {
+		g.Info.IgnoredIds = append(g.Info.IgnoredIds, cleanedId)
 
-	// If this ID is whitelisted, remove it from the whitelist, as these are mutually exclusive
-	if g.MemberOrRoleIsWhitelisted(cleanedId) {
-		err := g.RemoveMemberOrRoleFromWhitelist(cleanedId)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+		// Take the ID off the whitelist too, as these are mutually exclusive
+		// Both lists are changed before saving, so the ID can't be left on both if the save fails
+		g.Info.WhitelistIds = RemoveItem(g.Info.WhitelistIds, cleanedId)
+	})
 }
 
 // RemoveMemberOrRoleFromIgnored
@@ -448,9 +884,9 @@ func (g *Guild) RemoveMemberOrRoleFromIgnored(remId string) error {
 		return errors.New("id is not ignored in this guild; nothing to remove")
 	}
 
-	g.Info.IgnoredIds = RemoveItem(g.Info.IgnoredIds, cleanedId)
-	g.save()
-	return nil
+	return g.update(func() {
+		g.Info.IgnoredIds = RemoveItem(g.Info.IgnoredIds, cleanedId)
+	})
 }
 
 // ChannelIsWhitelisted
@@ -490,23 +926,21 @@ func (g *Guild) AddChannelToWhitelist(channelId string) error {
 	}
 
 	// Make sure it's not already in the whitelist
-	if g.ChannelIsWhitelisted(channel.ID) {
-		return errors.New("channel is already whitelisted")
+	// An empty whitelist allows every channel, so check the list itself rather than ChannelIsWhitelisted
+	for _, whitelisted := range g.Info.WhitelistedChannels {
+		if whitelisted == channel.ID {
+			return errors.New("channel is already whitelisted")
+		}
 	}
 
 	// Add the ID to the whitelist
-	g.Info.WhitelistedChannels = append(g.Info.WhitelistedChannels, channel.ID)
-	g.save()
-
-	// If this channel is ignored, remove it from the ignore list, as these are mutually exclusive
-	if g.ChannelIsIgnored(channel.ID) {
-		err := g.RemoveChannelFromIgnored(channel.ID)
-		if err != nil {
-			return err
-		}
-	}
+	return g.update(func() {
+		g.Info.WhitelistedChannels = append(g.Info.WhitelistedChannels, channel.ID)
 
-	return nil
+		// Take the channel off the ignore list too, as these are mutually exclusive
+		// Both lists are changed before saving, so the ID can't be left on both if the save fails
+		g.Info.IgnoredChannels = RemoveItem(g.Info.IgnoredChannels, channel.ID)
+	})
 }
 
 // RemoveChannelFromWhitelist
@@ -523,10 +957,9 @@ func (g *Guild) RemoveChannelFromWhitelist(channelId string) error {
 	}
 
 	// Remove the ID from the whitelist
-	g.Info.WhitelistedChannels = RemoveItem(g.Info.WhitelistedChannels, cleanedId)
-	g.save()
-
-	return nil
+	return g.update(func() {
+		g.Info.WhitelistedChannels = RemoveItem(g.Info.WhitelistedChannels, cleanedId)
+	})
 }
 
 // ChannelIsIgnored
@@ -571,18 +1004,13 @@ func (g *Guild) AddChannelToIgnored(channelId string) error {
 	}
 
 	// Add the ID to the ignored list
-	g.Info.IgnoredChannels = append(g.Info.IgnoredChannels, channel.ID)
-	g.save()
+	return g.update(func() {
+		g.Info.IgnoredChannels = append(g.Info.IgnoredChannels, channel.ID)
 
-	// If this channel is whitelisted, remove it from the whitelist, as these are mutually exclusive
-	if g.ChannelIsWhitelisted(channel.ID) {
-		err := g.RemoveChannelFromWhitelist(channel.ID)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+		// Take the channel off the whitelist too, as these are mutually exclusive
+		// Both lists are changed before saving, so the ID can't be left on both if the save fails
+		g.Info.WhitelistedChannels = RemoveItem(g.Info.WhitelistedChannels, channel.ID)
+	})
 }
 
 // RemoveChannelFromIgnored
@@ -599,10 +1027,9 @@ func (g *Guild) RemoveChannelFromIgnored(channelId string) error {
 	}
 
 	// Remove the ID from the ignore list
-	g.Info.IgnoredChannels = RemoveItem(g.Info.IgnoredChannels, cleanedId)
-	g.save()
-
-	return nil
+	return g.update(func() {
+		g.Info.IgnoredChannels = RemoveItem(g.Info.IgnoredChannels, cleanedId)
+	})
 }
 
 // IsGloballyDisabled
@@ -624,9 +1051,9 @@ func (g *Guild) EnableCommandGlobally(trigger string) error {
 		return errors.New("trigger is not disabled; nothing to enable")
 	}
 
-	g.Info.GlobalDisabledCommands = RemoveItem(g.Info.GlobalDisabledCommands, trigger)
-	g.save()
-	return nil
+	return g.update(func() {
+		g.Info.GlobalDisabledCommands = RemoveItem(g.Info.GlobalDisabledCommands, trigger)
+	})
 }
 
 // DisableCommandGlobally
@@ -636,9 +1063,9 @@ func (g *Guild) DisableCommandGlobally(command string) error {
 		return errors.New("command is not enabled; nothing to disable")
 	}
 
-	g.Info.GlobalDisabledCommands = append(g.Info.GlobalDisabledCommands, command)
-	g.save()
-	return nil
+	return g.update(func() {
+		g.Info.GlobalDisabledCommands = append(g.Info.GlobalDisabledCommands, command)
+	})
 }
 
 // CommandIsDisabledInChannel
@@ -685,16 +1112,15 @@ func (g *Guild) EnableCommandInChannel(command string, channelId string) error {
 		return errors.New("that command is not disabled in this channel; nothing to enable")
 	}
 
-	// Remove the trigger from THIS channel's list
-	g.Info.ChannelDisabledCommands[cleanedId] = RemoveItem(g.Info.ChannelDisabledCommands[cleanedId], command)
+	return g.update(func() {
+		// Remove the trigger from THIS channel's list
+		g.Info.ChannelDisabledCommands[cleanedId] = RemoveItem(g.Info.ChannelDisabledCommands[cleanedId], command)
 
-	// If there are no more items, delete the entire channel list, otherwise it will appear as null in the json
-	if len(g.Info.ChannelDisabledCommands[cleanedId]) == 0 {
-		delete(g.Info.ChannelDisabledCommands, cleanedId)
-	}
-
-	g.save()
-	return nil
+		// If there are no more items, delete the entire channel list, otherwise it will appear as null in the json
+		if len(g.Info.ChannelDisabledCommands[cleanedId]) == 0 {
+			delete(g.Info.ChannelDisabledCommands, cleanedId)
+		}
+	})
 }
 
 // DisableCommandInChannel
@@ -709,16 +1135,17 @@ func (g *Guild) DisableCommandInChannel(command string, channelId string) error
 		return errors.New("that trigger is already disabled in this channel; nothing to disable")
 	}
 
-	g.Info.ChannelDisabledCommands[cleanedId] = append(g.Info.ChannelDisabledCommands[cleanedId], command)
-	g.save()
-	return nil
+	return g.update(func() {
+		g.Info.ChannelDisabledCommands[cleanedId] = append(g.Info.ChannelDisabledCommands[cleanedId], command)
+	})
 }
 
 // SetDeletePolicy
 // Set the delete policy, then save the guild data
-func (g *Guild) SetDeletePolicy(policy bool) {
-	g.Info.DeletePolicy = policy
-	g.save()
+func (g *Guild) SetDeletePolicy(policy bool) error {
+	return g.update(func() {
+		g.Info.DeletePolicy = policy
+	})
 }
 
 // SetResponseChannel
@@ -726,18 +1153,51 @@ func (g *Guild) SetDeletePolicy(policy bool) {
 func (g *Guild) SetResponseChannel(channelId string) error {
 	// If channelId is blank,
 	if channelId == "" {
-		g.Info.ResponseChannelId = channelId
-		g.save()
-		return nil
+		return g.update(func() {
+			g.Info.ResponseChannelId = channelId
+		})
 	}
 	// Try grabbing the channel first (we don't use IsChannel since we need the real ID)
 	channel, err := g.GetChannel(channelId)
 	if err != nil {
 		return err
 	}
-	g.Info.ResponseChannelId = channel.ID
-	g.save()
-	return nil
+	return g.update(func() {
+		g.Info.ResponseChannelId = channel.ID
+	})
+}
+
+// SetGroupResponseChannel
+// Check that the channel exists, set the response channel for commands in a group, then save the guild data
+// Responses of commands in the group are sent there instead of the guild's response channel
+// A blank channelId removes the override, so the guild's response channel is used again
+func (g *Guild) SetGroupResponseChannel(group Group, channelId string) error {
+	if channelId == "" {
+		return g.update(func() {
+			delete(g.Info.GroupResponseChannels, group)
+		})
+	}
+	channel, err := g.GetChannel(channelId)
+	if err != nil {
+		return err
+	}
+	return g.update(func() {
+		if g.Info.GroupResponseChannels == nil {
+			g.Info.GroupResponseChannels = make(map[Group]string)
+		}
+		g.Info.GroupResponseChannels[group] = channel.ID
+	})
+}
+
+// GetResponseChannel
+// Get the channel responses of commands in a group are sent to
+// This is the group's response channel if it has one, otherwise the guild's response channel
+// A blank string means responses are sent in the channel the command was invoked from
+func (g *Guild) GetResponseChannel(group Group) string {
+	if channelId, ok := g.Info.GroupResponseChannels[group]; ok && channelId != "" {
+		return channelId
+	}
+	return g.Info.ResponseChannelId
 }
 
 // Kick
@@ -784,7 +1244,11 @@ func (g *Guild) PurgeChannel(channelId string, deleteCount int) (int, error) {
 	}
 
 	// Get the group of messages to delete
-	deleteGroup, err := Session.ChannelMessages(channel.ID, deleteCount, "", "", "")
+	var deleteGroup []*discordgo.Message
+	err = withRetry(func() (err error) {
+		deleteGroup, err = Session.ChannelMessages(channel.ID, deleteCount, "", "", "")
+		return
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -797,7 +1261,9 @@ func (g *Guild) PurgeChannel(channelId string, deleteCount int) (int, error) {
 	}
 
 	// Delete the messages
-	return len(messageIds), Session.ChannelMessagesBulkDelete(channel.ID, messageIds)
+	return len(messageIds), withRetry(func() error {
+		return Session.ChannelMessagesBulkDelete(channel.ID, messageIds)
+	})
 }
 
 // PurgeUserInChannel
@@ -828,7 +1294,11 @@ func (g *Guild) PurgeUserInChannel(userId string, channelId string, deleteCount
 		}
 
 		// Get 100 messages from the channel in this iteration
-		deleteGroup, err := Session.ChannelMessages(channel.ID, 100, lastId, "", "")
+		var deleteGroup []*discordgo.Message
+		err := withRetry(func() (err error) {
+			deleteGroup, err = Session.ChannelMessages(channel.ID, 100, lastId, "", "")
+			return
+		})
 		if err != nil {
 			// If we don't have any IDs to delete yet, return an error
 			// Break early otherwise
@@ -860,18 +1330,43 @@ func (g *Guild) PurgeUserInChannel(userId string, channelId string, deleteCount
 
 	// If we got messages to delete, delete them
 	if len(deleteIds) != 0 {
-		return len(deleteIds), Session.ChannelMessagesBulkDelete(channel.ID, deleteIds)
+		return len(deleteIds), withRetry(func() error {
+			return Session.ChannelMessagesBulkDelete(channel.ID, deleteIds)
+		})
 	} else {
 		return 0, nil
 	}
 
 }
 
+// canReadHistory
+// Check if a channel has messages, and if the bot has permission to read them
+func canReadHistory(channel *discordgo.Channel) bool {
+	if channel.Type != discordgo.ChannelTypeGuildText && channel.Type != discordgo.ChannelTypeGuildNews {
+		return false
+	}
+
+	// Prefer the state to avoid an API call per channel, but fall back to the API if the state is missing something
+	permissions, err := SessionForGuild(channel.GuildID).State.UserChannelPermissions(Session.State.User.ID, channel.ID)
+	if err != nil {
+		if permissions, err = Session.UserChannelPermissions(Session.State.User.ID, channel.ID); err != nil {
+			return false
+		}
+	}
+
+	required := int64(discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory)
+	return permissions&required == required
+}
+
 // PurgeUser
 // PurgeUser a user's messages in any channel
 func (g *Guild) PurgeUser(userId string, deleteCount int) (int, error) {
 	// Get all the channels in the guild
-	channels, err := Session.GuildChannels(g.ID)
+	var channels []*discordgo.Channel
+	err := withRetry(func() (err error) {
+		channels, err = Session.GuildChannels(g.ID)
+		return
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -884,6 +1379,11 @@ func (g *Guild) PurgeUser(userId string, deleteCount int) (int, error) {
 			break
 		}
 
+		// Skip channels without messages, or where the bot can't read the history, since fetching those messages would fail
+		if !canReadHistory(channel) {
+			continue
+		}
+
 		// Don't bother checking user ID, because this function will do it automatically, reducing API calls
 		numDeleted, err := g.PurgeUserInChannel(userId, channel.ID, deleteCount-totalDeleted)
 		if err != nil {
@@ -897,15 +1397,14 @@ func (g *Guild) PurgeUser(userId string, deleteCount int) (int, error) {
 
 // StoreString
 // Store a string to this guild's arbitrary storage
-func (g *Guild) StoreString(key string, value string) {
-	g.Info.Storage[key] = value
-	g.save()
+func (g *Guild) StoreString(key string, value string) error {
+	return g.setStorage(key, value)
 }
 
 // GetString
 // Retrieve a string from this guild's arbitrary storage, and error if the cast fails
 func (g *Guild) GetString(key string) (string, error) {
-	res, ok := g.Info.Storage[key].(string)
+	res, ok := g.storageValue(key).(string)
 	if !ok {
 		return "", errors.New("failed to cast the data to type \"string\"")
 	}
@@ -915,33 +1414,83 @@ func (g *Guild) GetString(key string) (string, error) {
 
 // StoreInt64
 // Store an int64 to this guild's arbitrary storage
-func (g *Guild) StoreInt64(key string, value int64) {
-	g.Info.Storage[key] = value
-	g.save()
+func (g *Guild) StoreInt64(key string, value int64) error {
+	return g.setStorage(key, value)
 }
 
 // GetInt64
 // Retrieve an int64 from this guild's arbitrary storage, and error if the cast fails
+// On failure (including a missing key), 0 is returned along with the error
+// 0 is also a valid stored value, so callers must check the error to tell the two apart
 func (g *Guild) GetInt64(key string) (int64, error) {
-	res, ok := g.Info.Storage[key].(int64)
-	if !ok {
-		return -1, errors.New("failed to cast the data to type \"int64\"")
+	return storageInt64(g.storageValue(key))
+}
+
+// storageInt64
+// Convert a value from a guild's arbitrary storage to an int64
+func storageInt64(value interface{}) (int64, error) {
+	switch res := value.(type) {
+	case int64:
+		return res, nil
+	case float64:
+		// JSON has no integer type, so an int64 comes back as a float64 after the guild is reloaded
+		if res == math.Trunc(res) {
+			return int64(res), nil
+		}
 	}
 
-	return res, nil
+	return 0, errors.New("failed to cast the data to type \"int64\"")
+}
+
+// IncrementInt64
+// Add delta to an int64 in this guild's arbitrary storage, then save the guild data and return the new value
+// A missing key counts as 0. The read, add, and write happen atomically, so concurrent increments are never lost
+func (g *Guild) IncrementInt64(key string, delta int64) (int64, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	value := int64(0)
+	if raw, ok := g.Info.Storage[key]; ok {
+		var err error
+		if value, err = storageInt64(raw); err != nil {
+			return 0, err
+		}
+	}
+
+	value += delta
+	g.Info.Storage[key] = value
+	return value, g.commitLocked()
+}
+
+// StoreFloat64
+// Store a float64 to this guild's arbitrary storage
+func (g *Guild) StoreFloat64(key string, value float64) error {
+	return g.setStorage(key, value)
+}
+
+// GetFloat64
+// Retrieve a float64 from this guild's arbitrary storage, and error if the cast fails
+func (g *Guild) GetFloat64(key string) (float64, error) {
+	switch res := g.storageValue(key).(type) {
+	case float64:
+		return res, nil
+	case int64:
+		return float64(res), nil
+	}
+
+	return 0, errors.New("failed to cast the data to type \"float64\"")
 }
 
 // StoreMap
 // Store a map to this guild's arbitrary storage
-func (g *Guild) StoreMap(key string, value map[string]interface{}) {
-	g.Info.Storage[key] = value
-	g.save()
+func (g *Guild) StoreMap(key string, value map[string]interface{}) error {
+	return g.setStorage(key, value)
 }
 
 // GetMap
 // Get a map from this guild's arbitrary storage, and error if the cast fails
 func (g *Guild) GetMap(key string) (map[string]interface{}, error) {
-	res, ok := g.Info.Storage[key].(map[string]interface{})
+	res, ok := g.storageValue(key).(map[string]interface{})
 	if !ok {
 		return nil, errors.New("failed to cast the data to type \"map[string]interface{}\"")
 	}
@@ -949,15 +1498,76 @@ func (g *Guild) GetMap(key string) (map[string]interface{}, error) {
 	return res, nil
 }
 
+// setStorage
+// Set a key in this guild's arbitrary storage, then save the guild data
+// The change and the save both happen under g.lock, so they can't race with other changes or saves
+func (g *Guild) setStorage(key string, value interface{}) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.Info.Storage[key] = value
+	return g.commitLocked()
+}
+
+// deleteStorage
+// Remove a key from this guild's arbitrary storage, then save the guild data
+func (g *Guild) deleteStorage(key string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	delete(g.Info.Storage, key)
+	return g.commitLocked()
+}
+
+// storageValue
+// Get the raw value of a key in this guild's arbitrary storage, or nil if it isn't set
+func (g *Guild) storageValue(key string) interface{} {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.Info.Storage[key]
+}
+
+// getStorageAs
+// Decode a value from this guild's arbitrary storage into the given pointer
+// Values that were stored as structs come back as generic maps and slices after a JSON reload,
+// so the value is round-tripped through JSON to reconstruct the concrete type either way
+// Returns false if the key is not present
+func (g *Guild) getStorageAs(key string, out interface{}) (bool, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.getStorageAsLocked(key, out)
+}
+
+// getStorageAsLocked
+// Like getStorageAs, for callers that already hold g.lock
+func (g *Guild) getStorageAsLocked(key string, out interface{}) (bool, error) {
+	raw, ok := g.Info.Storage[key]
+	if !ok || raw == nil {
+		return false, nil
+	}
+
+	jsonBytes, err := json.Marshal(raw)
+	if err != nil {
+		return true, err
+	}
+
+	return true, json.Unmarshal(jsonBytes, out)
+}
+
 // GetCommandUsage
 //// Compile the usage information for a single command, so it can be printed out
 func (g *Guild) GetCommandUsage(cmd CommandInfo) string {
+	return commandUsage(g.Info.Prefix, cmd)
+}
+
+// commandUsage
+// Compile the usage information for a single command, with the given prefix in front of the trigger
+// Used directly when there is no guild to take the prefix from
+func commandUsage(prefix string, cmd CommandInfo) string {
 	// Get the trigger for the command, and add the prefix to it
-	trigger := g.Info.Prefix + cmd.Trigger
+	trigger := prefix + cmd.Trigger
 
 	// If there are no usage examples, we only need to print the trigger, wrapped in code formatting
-	if len(cmd.Arguments.Keys()) == 0 {
-		return "```\n" + trigger + "\n```"
+	if cmd.Arguments == nil || len(cmd.Arguments.Keys()) == 0 {
+		return "```\n" + EscapeCodeBlock(trigger) + "\n```"
 	}
 
 	// Start building the output
@@ -967,7 +1577,7 @@ func (g *Guild) GetCommandUsage(cmd CommandInfo) string {
 	for _, arg := range cmd.Arguments.Keys() {
 		v, ok := cmd.Arguments.Get(arg)
 		if !ok {
-			return "```\n" + trigger + "\n```"
+			return "```\n" + EscapeCodeBlock(trigger) + "\n```"
 		}
 		argType := v.(*ArgInfo)
 		output += trigger + " <" + arg + "> (" + argType.Description + ") "
@@ -976,5 +1586,5 @@ func (g *Guild) GetCommandUsage(cmd CommandInfo) string {
 		}
 		cnt++
 	}
-	return "```\n" + output + "\n```"
+	return "```\n" + EscapeCodeBlock(output) + "\n```"
 }
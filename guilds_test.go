@@ -0,0 +1,245 @@
+package framework
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGetGuildConcurrent(t *testing.T) {
+	saves := useTestProvider(t)
+
+	const goroutines = 50
+	ids := []string{"100000000000000001", "100000000000000002", "100000000000000003"}
+
+	results := make([][]*Guild, len(ids))
+	for i := range results {
+		results[i] = make([]*Guild, goroutines)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		for j, id := range ids {
+			wg.Add(1)
+			go func(i int, j int, id string) {
+				defer wg.Done()
+				results[j][i] = getGuild(id)
+			}(i, j, id)
+		}
+	}
+	wg.Wait()
+
+	for j, id := range ids {
+		for i, g := range results[j] {
+			if g != results[j][0] {
+				t.Fatalf("goroutine %d got a different *Guild for %s", i, id)
+			}
+		}
+		if got := saves(id); got != 1 {
+			t.Errorf("guild %s was saved %d times, want exactly 1", id, got)
+		}
+	}
+
+	if got := len(AllGuilds()); got != len(ids) {
+		t.Errorf("AllGuilds() has %d guilds, want %d", got, len(ids))
+	}
+}
+
+func TestGetGuildWhileListing(t *testing.T) {
+	useTestProvider(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			getGuild(strconv.Itoa(200000000000000000 + i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			for _, g := range AllGuilds() {
+				_ = g.ID
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(AllGuilds()); got != 20 {
+		t.Errorf("AllGuilds() has %d guilds, want 20", got)
+	}
+}
+
+func TestGetInt64AfterJSONRoundTrip(t *testing.T) {
+	useTestProvider(t)
+	g := getGuild("300000000000000001")
+
+	if err := g.StoreInt64("counter", 1<<40+7); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.StoreFloat64("ratio", 0.25); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a save and reload, which turns every number into a float64
+	data, err := json.Marshal(g.Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info GuildInfo
+	if err = json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := &Guild{ID: g.ID, Info: info}
+
+	if value, err := reloaded.GetInt64("counter"); err != nil || value != 1<<40+7 {
+		t.Errorf("GetInt64 after reload = %d, %v; want %d", value, err, int64(1<<40+7))
+	}
+	if value, err := reloaded.GetFloat64("ratio"); err != nil || value != 0.25 {
+		t.Errorf("GetFloat64 after reload = %f, %v; want 0.25", value, err)
+	}
+	if value, err := reloaded.GetFloat64("counter"); err != nil || value != 1<<40+7 {
+		t.Errorf("GetFloat64 of an int64 = %f, %v", value, err)
+	}
+	if _, err := reloaded.GetInt64("ratio"); err == nil {
+		t.Error("GetInt64 accepted a fractional value")
+	}
+	if _, err := reloaded.GetInt64("missing"); err == nil {
+		t.Error("GetInt64 accepted a missing key")
+	}
+}
+
+func TestStorageConcurrentChanges(t *testing.T) {
+	useTestProvider(t)
+	g := getGuild("300000000000000002")
+
+	const goroutines = 50
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if _, err := g.IncrementInt64("count", 1); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			if err := g.StoreInt64("other_"+strconv.Itoa(i), int64(i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := g.AddWarning("400000000000000001", "400000000000000002", "test"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count, err := g.GetInt64("count"); err != nil || count != goroutines {
+		t.Errorf("count = %d, %v; want %d", count, err, goroutines)
+	}
+	if warnings, err := g.GetWarnings("400000000000000001"); err != nil || len(warnings) != goroutines {
+		t.Errorf("got %d warnings, %v; want %d", len(warnings), err, goroutines)
+	}
+}
+
+func TestDebouncedSaveWhileChanging(t *testing.T) {
+	saves := useTestProvider(t)
+	SetSaveMode(false, time.Millisecond)
+	t.Cleanup(func() { SetSaveMode(true) })
+
+	g := getGuild("300000000000000003")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			if err := g.SetPrefix(strconv.Itoa(i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if err := g.SetGroupResponseChannel("test", ""); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := Flush(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	before := saves(g.ID)
+	if err := g.SetDeletePolicy(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := saves(g.ID); got != before+1 {
+		t.Errorf("Flush saved the guild %d times, want 1", got-before)
+	}
+}
+
+func TestFlushSkipsDeletedGuild(t *testing.T) {
+	saves := useTestProvider(t)
+	SetSaveMode(false, time.Hour)
+	t.Cleanup(func() { SetSaveMode(true) })
+
+	g := getGuild("300000000000000004")
+	if err := g.SetPrefix("?"); err != nil {
+		t.Fatal(err)
+	}
+	before := saves(g.ID)
+
+	guildDeleteHandler(nil, &discordgo.GuildDelete{Guild: &discordgo.Guild{ID: g.ID}})
+	if err := Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := saves(g.ID); got != before {
+		t.Errorf("the deleted guild was saved %d times after it was deleted", got-before)
+	}
+}
+
+func TestWhitelistAndIgnoreStayExclusiveWhenSaveFails(t *testing.T) {
+	useTestProvider(t)
+	useFakeSession(t, func(req *http.Request) (int, string) {
+		if strings.Contains(req.URL.Path, "/members/") {
+			return http.StatusOK, `{"user":{"id":"400000000000000003"}}`
+		}
+		return http.StatusNotFound, `{"code":10013,"message":"Unknown User"}`
+	})
+
+	g := getGuild("300000000000000005")
+	const id = "400000000000000003"
+	if err := g.AddMemberOrRoleToIgnored(id); err != nil {
+		t.Fatal(err)
+	}
+
+	currentProvider.Save = func(g *Guild) error {
+		return errors.New("disk full")
+	}
+	if err := g.AddMemberOrRoleToWhitelist(id); err == nil {
+		t.Fatal("AddMemberOrRoleToWhitelist didn't return the save error")
+	}
+
+	whitelisted := len(g.Info.WhitelistIds) == 1 && g.Info.WhitelistIds[0] == id
+	ignored := len(g.Info.IgnoredIds) != 0
+	if !whitelisted || ignored {
+		t.Errorf("after a failed save, whitelist = %v and ignore list = %v; want only the whitelist", g.Info.WhitelistIds, g.Info.IgnoredIds)
+	}
+}
@@ -23,7 +23,10 @@ func addDGoHandlers() {
 		return
 	}
 
-	for _, handler := range dGOHandlers {
-		Session.AddHandler(handler)
+	// Every shard receives the events of its own guilds, so every shard needs the handlers
+	for _, shard := range Sessions {
+		for _, handler := range dGOHandlers {
+			shard.AddHandler(handler)
+		}
 	}
 }
@@ -0,0 +1,113 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// health.go
+// This file contains an optional HTTP server with liveness and readiness endpoints, e.g. for container orchestration
+
+// healthAddr
+// The address the health server listens on. Blank disables the health server
+var healthAddr = ""
+
+// healthServer
+// The running health server, if it is enabled
+var healthServer *http.Server
+
+// SetHealthAddr
+// Enable the health server on the given address (e.g. ":8080"). This must be called before Start
+// The server exposes /healthz, which succeeds while the process is up,
+// and /readyz, which succeeds once every shard's Discord session is open and at least one guild is loaded
+func SetHealthAddr(addr string) {
+	healthAddr = addr
+}
+
+// healthStatus
+// The body of every health endpoint response
+type healthStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Guilds    int    `json:"guilds"`
+}
+
+// currentHealth
+// Check if the bot is ready, and collect the status reported by the health endpoints
+func currentHealth() (healthStatus, bool) {
+	guildsLock.RLock()
+	status := healthStatus{Guilds: len(Guilds)}
+	guildsLock.RUnlock()
+
+	// Report the latency of the slowest shard
+	for _, shard := range Sessions {
+		shard.RLock()
+		if latency := shard.HeartbeatLatency().Milliseconds(); latency > status.LatencyMs {
+			status.LatencyMs = latency
+		}
+		shard.RUnlock()
+	}
+	return status, shardsReady() && status.Guilds > 0
+}
+
+// writeHealth
+// Write a health status as JSON
+func writeHealth(w http.ResponseWriter, code int, status healthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// startHealthServer
+// Start the health server in the background, if it is enabled
+func startHealthServer() {
+	if healthAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		status, _ := currentHealth()
+		status.Status = "ok"
+		writeHealth(w, http.StatusOK, status)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		status, ready := currentHealth()
+		if !ready {
+			status.Status = "not ready"
+			writeHealth(w, http.StatusServiceUnavailable, status)
+			return
+		}
+		status.Status = "ready"
+		writeHealth(w, http.StatusOK, status)
+	})
+
+	healthServer = &http.Server{
+		Addr:              healthAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		log.Infof("Health server listening on %s", healthAddr)
+		if err := healthServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Health server failed: %s", err)
+		}
+	}()
+}
+
+// stopHealthServer
+// Shut down the health server, if it is running
+func stopHealthServer() {
+	if healthServer == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := healthServer.Shutdown(ctx); err != nil {
+		log.Errorf("Failed to shut down the health server: %s", err)
+	}
+}
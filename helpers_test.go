@@ -0,0 +1,112 @@
+package framework
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeDiscord
+// An http.RoundTripper standing in for the Discord API, so tests never make real requests
+// Every request is recorded, and answered by respond (or with an empty JSON object if respond is nil)
+type fakeDiscord struct {
+	mu       sync.Mutex
+	requests []string
+	respond  func(req *http.Request) (int, string)
+}
+
+func (f *fakeDiscord) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req.Method+" "+req.URL.Path)
+	f.mu.Unlock()
+
+	status, body := http.StatusOK, "{}"
+	if f.respond != nil {
+		status, body = f.respond(req)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// Requests returns the "METHOD /path" of every request made so far
+func (f *fakeDiscord) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+// newFakeSession
+// Create a session that sends its requests to a fakeDiscord
+func newFakeSession(respond func(req *http.Request) (int, string)) (*discordgo.Session, *fakeDiscord) {
+	fake := &fakeDiscord{respond: respond}
+	s, _ := discordgo.New("Bot test")
+	s.Client = &http.Client{Transport: fake}
+	s.MaxRestRetries = 0
+	return s, fake
+}
+
+// useFakeSession
+// Replace the framework's Session with one that talks to a fakeDiscord for the duration of the test
+func useFakeSession(t *testing.T, respond func(req *http.Request) (int, string)) *fakeDiscord {
+	t.Helper()
+	s, fake := newFakeSession(respond)
+
+	oldSession := Session
+	Session = s
+	t.Cleanup(func() {
+		Session = oldSession
+	})
+	return fake
+}
+
+// useTestProvider
+// Replace the guild provider and the known guilds for the duration of the test
+// Saves are counted rather than written; the returned function reports how many times a guild was saved
+func useTestProvider(t *testing.T) func(guildId string) int {
+	t.Helper()
+	var mu sync.Mutex
+	saves := make(map[string]int)
+
+	oldProvider, oldGuilds := currentProvider, Guilds
+	currentProvider = GuildProvider{
+		Save: func(g *Guild) error {
+			// Marshal the guild like a real provider, so the race detector sees unguarded changes
+			if _, err := json.Marshal(g.Info); err != nil {
+				return err
+			}
+			mu.Lock()
+			saves[g.ID]++
+			mu.Unlock()
+			return nil
+		},
+		Load: func() map[string]*Guild {
+			return make(map[string]*Guild)
+		},
+	}
+	guildsLock.Lock()
+	Guilds = make(map[string]*Guild)
+	guildsLock.Unlock()
+
+	t.Cleanup(func() {
+		currentProvider = oldProvider
+		guildsLock.Lock()
+		Guilds = oldGuilds
+		guildsLock.Unlock()
+	})
+
+	return func(guildId string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return saves[guildId]
+	}
+}
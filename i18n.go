@@ -0,0 +1,139 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// i18n.go
+// This file contains message catalogs, which allow response text to be translated into a guild's locale
+
+// DefaultLocale
+// The locale used for guilds that have not set one, and as a fallback for missing translations
+var DefaultLocale = "en-US"
+
+// catalogs
+// A map of locales to their message catalog, which is a map of message keys to (format) strings
+var catalogs = make(map[string]map[string]string)
+
+// catalogsLock
+// Guards catalogs, since catalogs may be added while commands are translating responses
+var catalogsLock sync.RWMutex
+
+// catalogDirectory
+// The directory message catalogs are loaded from when the bot starts
+var catalogDirectory = ""
+
+// SetCatalogDirectory
+// Sets the directory message catalogs are loaded from when the bot starts
+// See LoadCatalogs for the expected layout of the directory
+func SetCatalogDirectory(dir string) {
+	catalogDirectory = dir
+}
+
+// AddCatalog
+// Add messages to the catalog of a locale
+// Messages with a key that already exists in the catalog replace the old message
+func AddCatalog(locale string, messages map[string]string) {
+	catalogsLock.Lock()
+	defer catalogsLock.Unlock()
+
+	if catalogs[locale] == nil {
+		catalogs[locale] = make(map[string]string)
+	}
+	for key, message := range messages {
+		catalogs[locale][key] = message
+	}
+}
+
+// LoadCatalogs
+// Load every message catalog in a directory
+// Each catalog is a JSON object of message keys to messages, named after its locale (e.g. "en-US.json", "de.json")
+func LoadCatalogs(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+
+		var messages map[string]string
+		if err = json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("failed to parse catalog %s: %w", file.Name(), err)
+		}
+
+		AddCatalog(strings.TrimSuffix(file.Name(), ".json"), messages)
+	}
+
+	return nil
+}
+
+// loadCatalogs
+// Load the message catalogs from the catalog directory, if one was set
+func loadCatalogs() {
+	if catalogDirectory == "" {
+		return
+	}
+
+	if err := LoadCatalogs(catalogDirectory); err != nil {
+		log.Errorf("Failed to load message catalogs: %s", err)
+		return
+	}
+
+	catalogsLock.RLock()
+	log.Infof("Loaded message catalogs for %d locale(s)", len(catalogs))
+	catalogsLock.RUnlock()
+}
+
+// lookupMessage
+// Get a message from the catalog of a locale
+// If the locale is regional (e.g. "es-ES") and has no such message, the base language ("es") is tried as well
+func lookupMessage(locale string, key string) (string, bool) {
+	catalogsLock.RLock()
+	defer catalogsLock.RUnlock()
+
+	if message, ok := catalogs[locale][key]; ok {
+		return message, true
+	}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		if message, ok := catalogs[base][key]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// Translate
+// Get the message for a key in the guild's locale, formatted with the given arguments
+// Falls back to DefaultLocale if the guild's locale has no such message, and to the key itself if no locale does
+// guild may be nil, e.g. in DMs, in which case DefaultLocale is used
+func Translate(guild *Guild, key string, args ...interface{}) string {
+	locale := DefaultLocale
+	if guild != nil && guild.Info.Locale != "" {
+		locale = guild.Info.Locale
+	}
+
+	message, ok := lookupMessage(locale, key)
+	if !ok {
+		if message, ok = lookupMessage(DefaultLocale, key); !ok {
+			message = key
+		}
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
@@ -1,7 +1,7 @@
 package framework
 
 import (
-	"runtime"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -28,18 +28,48 @@ var genericError = "error executing command"
 // Creates a slash command struct
 // todo work on sub command stuff
 func createSlashCommandStruct(info *CommandInfo) (st *discordgo.ApplicationCommand) {
+	// Hide guild-only commands from DMs at the Discord UI level
+	var dmPermission *bool
+	if info.GuildOnly {
+		dmPermission = ToPtr(false)
+	}
+	var nsfw *bool
+	if info.NSFW {
+		nsfw = ToPtr(true)
+	}
+
 	if info.Arguments == nil || len(info.Arguments.Keys()) < 1 {
 		st = &discordgo.ApplicationCommand{
-			Name:        info.Trigger,
-			Description: info.Description,
+			Name:                     info.Trigger,
+			NameLocalizations:        localizationsPtr(info.NameLocalizations),
+			Description:              info.Description,
+			DescriptionLocalizations: localizationsPtr(info.DescriptionLocalizations),
+			DMPermission:             dmPermission,
+			DefaultMemberPermissions: info.DefaultMemberPermissions,
+			NSFW:                     nsfw,
 		}
 		return
 	}
 	st = &discordgo.ApplicationCommand{
-		Name:        info.Trigger,
-		Description: info.Description,
-		Options:     make([]*discordgo.ApplicationCommandOption, len(info.Arguments.Keys())),
+		Name:                     info.Trigger,
+		NameLocalizations:        localizationsPtr(info.NameLocalizations),
+		Description:              info.Description,
+		DescriptionLocalizations: localizationsPtr(info.DescriptionLocalizations),
+		DMPermission:             dmPermission,
+		DefaultMemberPermissions: info.DefaultMemberPermissions,
+		NSFW:                     nsfw,
+		Options:                  createSlashCommandOptions(info),
 	}
+	return
+}
+
+// createSlashCommandOptions
+// Creates the slash command options for all the args of a command
+func createSlashCommandOptions(info *CommandInfo) []*discordgo.ApplicationCommandOption {
+	if info.Arguments == nil {
+		return nil
+	}
+	options := make([]*discordgo.ApplicationCommandOption, len(info.Arguments.Keys()))
 	for i, k := range info.Arguments.Keys() {
 		v, _ := info.Arguments.Get(k)
 		vv := v.(*ArgInfo)
@@ -55,6 +85,9 @@ func createSlashCommandStruct(info *CommandInfo) (st *discordgo.ApplicationComma
 			Description: vv.Description,
 			Required:    vv.Required,
 		}
+		if vv.Autocomplete && vv.Choices == nil {
+			optionStruct.Autocomplete = true
+		}
 		if vv.Choices != nil {
 			optionStruct.Choices = make([]*discordgo.ApplicationCommandOptionChoice, len(vv.Choices))
 			for i, k := range vv.Choices {
@@ -64,22 +97,46 @@ func createSlashCommandStruct(info *CommandInfo) (st *discordgo.ApplicationComma
 				}
 			}
 		}
-		st.Options[i] = &optionStruct
+		options[i] = &optionStruct
 	}
-	return
+	return options
+}
+
+// localizationsPtr
+// discordgo wants a pointer to the localization map, and a nil pointer for commands without any localizations
+func localizationsPtr(localizations map[discordgo.Locale]string) *map[discordgo.Locale]string {
+	if len(localizations) == 0 {
+		return nil
+	}
+	return &localizations
 }
 
 // Creates a slash subcmd struct
 func createSlashSubCmdStruct(info *CommandInfo, childCmds map[string]Command) (st *discordgo.ApplicationCommand) {
+	// Hide guild-only commands from DMs at the Discord UI level
+	var dmPermission *bool
+	if info.GuildOnly {
+		dmPermission = ToPtr(false)
+	}
+	var nsfw *bool
+	if info.NSFW {
+		nsfw = ToPtr(true)
+	}
+
 	st = &discordgo.ApplicationCommand{
-		Name:        info.Trigger,
-		Description: info.Description,
-		Options:     make([]*discordgo.ApplicationCommandOption, len(childCmds)),
+		Name:                     info.Trigger,
+		NameLocalizations:        localizationsPtr(info.NameLocalizations),
+		Description:              info.Description,
+		DescriptionLocalizations: localizationsPtr(info.DescriptionLocalizations),
+		DMPermission:             dmPermission,
+		DefaultMemberPermissions: info.DefaultMemberPermissions,
+		NSFW:                     nsfw,
+		Options:                  make([]*discordgo.ApplicationCommandOption, len(childCmds)),
 	}
 	currentPos := 0
 	for _, v := range childCmds {
 		// Stupid inline thing
-		if ar, _ := v.Info.Arguments.Get(v.Info.Arguments.Keys()[0]); ar.(*ArgInfo).TypeGuard == SubCmdGrp {
+		if isSubCmdGrp(v.Info) {
 
 		} else {
 			//Pixel:
@@ -89,9 +146,21 @@ func createSlashSubCmdStruct(info *CommandInfo, childCmds map[string]Command) (s
 			currentPos++
 		}
 	}
+	// Sub command groups are skipped, so don't leave empty options at the end
+	st.Options = st.Options[:currentPos]
 	return st
 }
 
+// isSubCmdGrp
+// Check if a child command is a sub command group, which is marked by its first argument
+func isSubCmdGrp(info CommandInfo) bool {
+	if info.Arguments == nil || len(info.Arguments.Keys()) == 0 {
+		return false
+	}
+	ar, _ := info.Arguments.Get(info.Arguments.Keys()[0])
+	return ar.(*ArgInfo).TypeGuard == SubCmdGrp
+}
+
 // -- Interaction Handlers --
 
 // handleInteraction
@@ -103,64 +172,225 @@ func handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		break
 	case discordgo.InteractionMessageComponent:
 		handleMessageComponents(s, i)
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		handleAutoComplete(s, i)
 	}
 	return
 }
 
+// interactionUser
+// Get the user that triggered an interaction
+// In guilds the user is attached to the member, but in DMs and user-installed apps the member is nil and only the user is set
+func interactionUser(i *discordgo.Interaction) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
 // handleInteractionCommand
 // Handles a slash command
 func handleInteractionCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	g := getGuild(i.GuildID)
+	user := interactionUser(i.Interaction)
 
 	trigger := i.ApplicationCommandData().Name
-	if !IsAdmin(i.Member.User.ID) {
+
+	// The slash command may be stale, e.g. it was renamed or removed without re-registering slash commands
+	command, ok := getCommand(trigger)
+	if !ok {
+		log.Warningf("Received unknown slash command \"%s\"", trigger)
+		ephemeralErrorResponse(i.Interaction, "This command is no longer available.")
+		return
+	}
+
+	// Make sure the command is allowed in this context (guild or DM)
+	if reason := command.Info.contextRejection(i.GuildID == ""); reason != "" {
+		ephemeralErrorResponse(i.Interaction, reason)
+		return
+	}
+
+	// Only do further checks if the user is not a bot admin
+	// DMs and user-installed apps have no guild, so the guild-scoped checks are skipped entirely
+	if !IsAdmin(user.ID) && i.GuildID != "" {
+		trigger = strings.ToLower(trigger)
+
 		// Ignore the command if it is globally disabled
 		if g.IsGloballyDisabled(trigger) {
-			ErrorResponse(i.Interaction, "Command is globally disabled", trigger)
+			ephemeralErrorResponse(i.Interaction, "This command is globally disabled.")
 			return
 		}
 
 		// Ignore the command if this channel has blocked the command
 		if g.CommandIsDisabledInChannel(trigger, i.ChannelID) {
-			ErrorResponse(i.Interaction, "Command is disabled in this channel!", trigger)
+			ephemeralErrorResponse(i.Interaction, "This command is disabled in this channel.")
 			return
 		}
 
 		// Ignore any message if the user is banned from using the bot
-		if !g.MemberOrRoleIsWhitelisted(i.Member.User.ID) || g.MemberOrRoleIsIgnored(i.Member.User.ID) {
+		if !g.MemberOrRoleIsWhitelisted(user.ID) || g.MemberOrRoleIsIgnored(user.ID) {
+			ephemeralErrorResponse(i.Interaction, "You are not allowed to use commands in this server.")
 			return
 		}
 
 		// Ignore the message if this channel is not whitelisted, or if it is ignored
 		if !g.ChannelIsWhitelisted(i.ChannelID) || g.ChannelIsIgnored(i.ChannelID) {
+			ephemeralErrorResponse(i.Interaction, "Commands cannot be used in this channel.")
 			return
 		}
 	}
 
-	command := commands[trigger]
-	if IsAdmin(i.Member.User.ID) || command.Info.Public || g.IsMod(i.Member.User.ID) {
+	if IsAdmin(user.ID) || command.Info.Public || g.IsMod(user.ID) {
 		// Check if the command is public, or if the current user is a bot moderator
 		// Bot admins supercede both checks
 
+		// Usage is counted for the top-level command, so prefix and slash invocations of the same command add up
+		usageTrigger := command.Info.Trigger
+
+		// Run the handler named after the chosen sub command, or the chosen child command if this is a parent command
+		options := i.ApplicationCommandData().Options
+		function := command.defaultFunction()
+		path, subOptions := subCommandPath(options)
+		if handler, ok := getCommandHandler(command.Info.Trigger, subCommandHandlerNames(path)...); ok {
+			function = handler
+			options = subOptions
+		} else if childCmd, childOptions, ok := findSubCommand(command, options); ok {
+			command = childCmd
+			function = childCmd.Function
+			options = childOptions
+		} else {
+			// The default handler gets the options of the chosen sub command, if there is one
+			options = subOptions
+		}
+
+		// Acknowledge the interaction before running the command, so slow commands don't time out
+		deferred := false
+		if command.Info.DeferReply {
+			ack := &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			}
+			if command.Info.DeferEphemeral {
+				ack.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+			}
+			err := Session.InteractionRespond(i.Interaction, ack)
+			if err != nil {
+				log.Errorf("Failed to defer the response to command %s: %s", command.Info.Trigger, err)
+			} else {
+				deferred = true
+			}
+		}
+
+		// Count the usage after the acknowledgement, since saving the count can write to disk
+		recordGuildUsage(g, usageTrigger)
+
 		defer handleSlashCommandError(*i.Interaction)
-		command.Function(&Context{
-			Guild:       g,
-			Cmd:         command.Info,
-			Args:        *ParseInteractionArgs(i.ApplicationCommandData().Options),
-			Interaction: i.Interaction,
-			Message: &discordgo.Message{
+		measureCommand(command.Info.Trigger, true, func() {
+			applyMiddleware(function)(&Context{
+				Guild:       g,
+				Cmd:         command.Info,
+				Args:        *ParseInteractionArgs(options),
+				Interaction: i.Interaction,
+				Message: &discordgo.Message{
+					Member:    i.Member,
+					Author:    user,
+					ChannelID: i.ChannelID,
+					GuildID:   i.GuildID,
+					Content:   "",
+				},
+				deferred:          deferred,
+				deferredEphemeral: deferred && command.Info.DeferEphemeral,
+			})
+		})
+		return
+	}
+
+	ephemeralErrorResponse(i.Interaction, "You do not have permission to use this command.")
+}
+
+// subCommandPath
+// Get the names of the sub command group (if any) and sub command chosen in a slash command, along with the options passed to the sub command
+// If no sub command was chosen, the path is empty and the options are returned as they are
+func subCommandPath(options []*discordgo.ApplicationCommandInteractionDataOption) ([]string, []*discordgo.ApplicationCommandInteractionDataOption) {
+	var path []string
+	for _, option := range options {
+		switch option.Type {
+		case discordgo.ApplicationCommandOptionSubCommandGroup:
+			// A group holds exactly one chosen sub command
+			groupPath, groupOptions := subCommandPath(option.Options)
+			return append(append(path, option.Name), groupPath...), groupOptions
+		case discordgo.ApplicationCommandOptionSubCommand:
+			return append(path, option.Name), option.Options
+		}
+	}
+	return path, options
+}
+
+// subCommandHandlerNames
+// Get the names of the handlers that can handle a sub command path, most specific first; see AddCommandHandler
+func subCommandHandlerNames(path []string) []string {
+	if len(path) == 0 {
+		return nil
+	}
+	if len(path) == 1 {
+		return path
+	}
+	return []string{strings.Join(path, " "), path[len(path)-1]}
+}
+
+// findSubCommand
+// Find the child command for the sub command chosen in a slash command, along with the options passed to it
+// Child commands are matched by their trigger, so a child's trigger must be the name of its sub command
+// Sub commands in a group are matched by the name of the sub command
+func findSubCommand(parent Command, options []*discordgo.ApplicationCommandInteractionDataOption) (Command, []*discordgo.ApplicationCommandInteractionDataOption, bool) {
+	path, subOptions := subCommandPath(options)
+	if len(path) == 0 {
+		return parent, options, false
+	}
+	name := path[len(path)-1]
+	for trigger, childCmd := range childCommands[strings.ToLower(parent.Info.Trigger)] {
+		if strings.EqualFold(trigger, name) {
+			return childCmd, subOptions, true
+		}
+	}
+	return parent, options, false
+}
+
+// handleMessageComponents
+// Handles a message component (e.g. a button click) by running the handler registered for its custom ID
+func handleMessageComponents(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if handler, ok := getComponentHandler(i.MessageComponentData().CustomID); ok {
+		// The context's message is the message the component is attached to, so the handler can see its embeds and components
+		// Note that its author is the bot; use interactionUser to get the user who used the component
+		message := i.Message
+		if message == nil {
+			message = &discordgo.Message{
 				Member:    i.Member,
-				Author:    i.Member.User,
+				Author:    interactionUser(i.Interaction),
 				ChannelID: i.ChannelID,
 				GuildID:   i.GuildID,
 				Content:   "",
-			},
+			}
+		}
+
+		// Components used in DMs and user-installed apps have no guild, so don't make up one
+		var g *Guild
+		if i.GuildID != "" {
+			g = getGuild(i.GuildID)
+		}
+
+		defer handleSlashCommandError(*i.Interaction)
+		handler(&Context{
+			Guild:       g,
+			Interaction: i.Interaction,
+			Message:     message,
 		})
 		return
 	}
-}
 
-func handleMessageComponents(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// The message the component is attached to may not have an embed to update
+	if i.Message == nil || len(i.Message.Embeds) == 0 {
+		return
+	}
 	content := "Currently testing customid " + i.MessageComponentData().CustomID
 	i.Message.Embeds[0].Description = content
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -200,7 +430,7 @@ func ParseInteractionArgsR(options []*discordgo.ApplicationCommandInteractionDat
 	for _, v := range options {
 		(*args)[v.Name] = CommandArg{
 			info:  ArgInfo{},
-			Value: v.StringValue(),
+			Value: v.Value,
 		}
 		if v.Options != nil {
 			ParseInteractionArgsR(v.Options, *&args)
@@ -210,28 +440,47 @@ func ParseInteractionArgsR(options []*discordgo.ApplicationCommandInteractionDat
 
 // -- :shrug: --
 
+// ListGuildSlashCommands
+// Lists all the slash commands registered in a guild (or globally, if guildID is blank)
+func ListGuildSlashCommands(guildID string) ([]*discordgo.ApplicationCommand, error) {
+	return Session.ApplicationCommands(Session.State.User.ID, guildID)
+}
+
 // RemoveGuildSlashCommands
-// Removes all guild slash commands.
-func RemoveGuildSlashCommands(guildID string) {
-	commands, err := Session.ApplicationCommands(Session.State.User.ID, guildID)
+// Removes all guild slash commands, and returns the names of the removed commands.
+// In dry run mode, nothing is removed; the names of the commands that would be removed are logged and returned instead.
+func RemoveGuildSlashCommands(guildID string, dryRun ...bool) ([]string, error) {
+	commands, err := ListGuildSlashCommands(guildID)
 	if err != nil {
 		log.Errorf("Error getting all slash commands %s", err)
-		return
+		return nil, err
 	}
+
+	var removed []string
+	if len(dryRun) > 0 && dryRun[0] {
+		for _, k := range commands {
+			log.Infof("[DRY RUN] Would delete slash command %s %s in guild %s", k.Name, k.ID, guildID)
+			removed = append(removed, k.Name)
+		}
+		return removed, nil
+	}
+
 	for _, k := range commands {
 		err = Session.ApplicationCommandDelete(Session.State.User.ID, guildID, k.ID)
 		if err != nil {
 			log.Errorf("error deleting slash command %s %s %s", k.Name, k.ID, err)
 			continue
 		}
+		removed = append(removed, k.Name)
 	}
+	return removed, nil
 }
 
 func handleSlashCommandError(i discordgo.Interaction) {
 	if r := recover(); r != nil {
 		log.Warningf("Recovering from panic: %s", r)
 		log.Warningf("Sending Error report to admins")
-		SendErrorReport(i.GuildID, i.ChannelID, i.Member.User.ID, "Error!", r.(runtime.Error))
+		SendErrorReport(i.GuildID, i.ChannelID, interactionUser(&i).ID, "Error!", recoveredError(r), panicStack())
 		message, err := Session.InteractionResponseEdit(&i, &discordgo.WebhookEdit{
 			Content: &genericError,
 		})
@@ -244,6 +493,7 @@ func handleSlashCommandError(i discordgo.Interaction) {
 				},
 			})
 			log.Errorf("err sending message %s", err)
+			return
 		}
 		Session.ChannelMessageDelete(i.ChannelID, message.ID)
 		return
@@ -0,0 +1,222 @@
+package framework
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestComponentWithoutMember(t *testing.T) {
+	useTestProvider(t)
+
+	const customId = "test_component_without_member"
+	var got *Context
+	AddComponentHandler(customId, func(ctx *Context) {
+		got = ctx
+	})
+	t.Cleanup(func() { RemoveComponentHandler(customId) })
+
+	user := &discordgo.User{ID: "500000000000000001", Username: "dm-user"}
+	handleMessageComponents(nil, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:      discordgo.InteractionMessageComponent,
+		ChannelID: "500000000000000002",
+		User:      user,
+		Data:      discordgo.MessageComponentInteractionData{CustomID: customId},
+	}})
+
+	if got == nil {
+		t.Fatal("the component handler was not called")
+	}
+	if author := got.Author(); author == nil || author.ID != user.ID {
+		t.Errorf("ctx.Author() = %v, want %s", author, user.ID)
+	}
+	if got.Guild != nil {
+		t.Errorf("ctx.Guild = %+v, want nil for a component used outside a guild", got.Guild)
+	}
+	if !got.IsDM() {
+		t.Error("a component used outside a guild is not treated as a DM")
+	}
+	if got.ChannelID() != "500000000000000002" {
+		t.Errorf("ctx.ChannelID() = %q", got.ChannelID())
+	}
+}
+
+// autocompleteInteraction
+// Create an autocomplete interaction for a command with the given options
+func autocompleteInteraction(name string, options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommandAutocomplete,
+		User: &discordgo.User{ID: "500000000000000001"},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:    name,
+			Options: options,
+		},
+	}}
+}
+
+// addTestCommand
+// Register a command for the duration of the test
+func addTestCommand(t *testing.T, info *CommandInfo) {
+	t.Helper()
+	AddCommand(info, func(ctx *Context) {})
+	t.Cleanup(func() { removeCommand(info.Trigger) })
+}
+
+func TestAutocompleteMixedCaseTrigger(t *testing.T) {
+	useTestProvider(t)
+	addTestCommand(t, CreateCommandInfo("MixedCase", "", true, "").
+		AddArg("query", String, ArgOption, "", true, "").
+		SetAutocomplete("query"))
+
+	called := false
+	AddAutoCompleteHandler("MixedCase", func(ctx *Context) {
+		called = true
+	})
+	t.Cleanup(func() { delete(autocompleteHandlers, "mixedcase") })
+
+	// Discord sends the name the slash command was registered with, which may be in any case
+	for _, name := range []string{"MixedCase", "mixedcase"} {
+		called = false
+		handleAutoComplete(nil, autocompleteInteraction(name, []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "query", Type: discordgo.ApplicationCommandOptionString, Value: "ab", Focused: true},
+		}))
+		if !called {
+			t.Errorf("the autocomplete handler was not called for %q", name)
+		}
+	}
+}
+
+func TestAutocompleteNestedFocusedOption(t *testing.T) {
+	useTestProvider(t)
+	addTestCommand(t, CreateCommandInfo("nested", "", true, "").
+		AddArg("config", SubCmdGrp, ArgOption, "", true, ""))
+
+	var focusedArgs []string
+	for _, arg := range []string{"key", "value"} {
+		arg := arg
+		AddArgAutoCompleteHandler("nested", arg, func(ctx *Context) {
+			focusedArgs = append(focusedArgs, arg)
+			if focused := ctx.FocusedOption(); focused == nil || focused.Name != arg {
+				t.Errorf("ctx.FocusedOption() = %v, want %s", focused, arg)
+			}
+		})
+	}
+	t.Cleanup(func() { delete(argAutocompleteHandlers, "nested") })
+
+	// nested config set <key> <value>, with the focused arg two levels deep
+	options := func(focused string) []*discordgo.ApplicationCommandInteractionDataOption {
+		return []*discordgo.ApplicationCommandInteractionDataOption{{
+			Name: "config",
+			Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Name: "set",
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: "key", Type: discordgo.ApplicationCommandOptionString, Value: "pre", Focused: focused == "key"},
+					{Name: "value", Type: discordgo.ApplicationCommandOptionString, Value: "on", Focused: focused == "value"},
+				},
+			}},
+		}}
+	}
+
+	handleAutoComplete(nil, autocompleteInteraction("nested", options("value")))
+	handleAutoComplete(nil, autocompleteInteraction("nested", options("key")))
+
+	if len(focusedArgs) != 2 || focusedArgs[0] != "value" || focusedArgs[1] != "key" {
+		t.Errorf("handlers were called for %v, want [value key]", focusedArgs)
+	}
+}
+
+func TestSubCommandHandlers(t *testing.T) {
+	useTestProvider(t)
+	useFakeSession(t, nil)
+
+	var called string
+	var args Arguments
+	handler := func(name string) BotFunction {
+		return func(ctx *Context) {
+			called = name
+			args = ctx.Args
+		}
+	}
+
+	info := CreateCommandInfo("cfg", "", true, "")
+	AddCommand(info, handler("function"))
+	t.Cleanup(func() { removeCommand("cfg") })
+	AddCommandHandler("cfg", "add", handler("add"))
+	AddCommandHandler("cfg", "channel set", handler("channel set"))
+	AddCommandHandler("cfg", "reset", handler("reset"))
+
+	value := func(name string) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: "v"}
+	}
+	sub := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options}
+	}
+	group := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommandGroup, Options: options}
+	}
+
+	tests := []struct {
+		name    string
+		options []*discordgo.ApplicationCommandInteractionDataOption
+		want    string
+		arg     string
+	}{
+		{"sub command", []*discordgo.ApplicationCommandInteractionDataOption{sub("add", value("key"))}, "add", "key"},
+		{"sub command in a group", []*discordgo.ApplicationCommandInteractionDataOption{group("channel", sub("set", value("id")))}, "channel set", "id"},
+		{"sub command name in a group", []*discordgo.ApplicationCommandInteractionDataOption{group("role", sub("reset", value("id")))}, "reset", "id"},
+		{"no handler", []*discordgo.ApplicationCommandInteractionDataOption{sub("remove", value("key"))}, "function", "key"},
+		{"no sub command", []*discordgo.ApplicationCommandInteractionDataOption{value("key")}, "function", "key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called, args = "", nil
+			handleInteractionCommand(nil, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+				Type: discordgo.InteractionApplicationCommand,
+				User: &discordgo.User{ID: "500000000000000001"},
+				Data: discordgo.ApplicationCommandInteractionData{Name: "cfg", Options: tt.options},
+			}})
+			if called != tt.want {
+				t.Errorf("the %q handler was called, want %q", called, tt.want)
+			}
+			if _, ok := args[tt.arg]; !ok {
+				t.Errorf("the handler didn't get the %q arg: %v", tt.arg, args)
+			}
+		})
+	}
+
+	// A default handler replaces the function given to AddCommand
+	AddCommandHandler("cfg", DefaultHandler, handler("default"))
+	called = ""
+	handleInteractionCommand(nil, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		User: &discordgo.User{ID: "500000000000000001"},
+		Data: discordgo.ApplicationCommandInteractionData{Name: "cfg", Options: []*discordgo.ApplicationCommandInteractionDataOption{sub("remove")}},
+	}})
+	if called != "default" {
+		t.Errorf("the %q handler was called, want the default handler", called)
+	}
+}
+
+func TestAutocompleteHandlerPrefix(t *testing.T) {
+	useTestProvider(t)
+	addTestCommand(t, CreateCommandInfo("prefixed", "", true, "").
+		AddArg("query", String, ArgOption, "", true, "").
+		SetAutocomplete("query"))
+
+	called := ""
+	AddCommandHandler("prefixed", AutocompleteHandlerPrefix+"query", func(ctx *Context) { called = "query" })
+	AddCommandHandler("prefixed", AutocompleteHandlerPrefix, func(ctx *Context) { called = "command" })
+
+	for _, tt := range []struct{ arg, want string }{{"query", "query"}, {"other", "command"}} {
+		called = ""
+		handleAutoComplete(nil, autocompleteInteraction("prefixed", []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: tt.arg, Type: discordgo.ApplicationCommandOptionString, Value: "ab", Focused: true},
+		}))
+		if called != tt.want {
+			t.Errorf("autocomplete for %q called the %q handler, want %q", tt.arg, called, tt.want)
+		}
+	}
+}
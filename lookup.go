@@ -0,0 +1,259 @@
+package framework
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/dlclark/regexp2"
+)
+
+// lookup.go
+// This file contains functions for finding members, channels and roles in a guild by what moderators actually type, like names
+
+// memberSearchLimit
+// The maximum amount of members Discord returns for a member search
+const memberSearchLimit = 1000
+
+// maxCandidates
+// The maximum amount of candidates listed when a query is ambiguous
+const maxCandidates = 10
+
+// isIdOrMention
+// Determine whether a query is a plain ID, or a mention matching the given regex
+func isIdOrMention(query string, mention *regexp2.Regexp) bool {
+	if isId, _ := MentionStringRegexes["id"].MatchString(query); isId {
+		return true
+	}
+	isMention, _ := mention.MatchString(query)
+	return isMention
+}
+
+// ambiguousError
+// Create the error for a query that matched more than one thing, listing (some of) the candidates
+func ambiguousError(query string, candidates []string) error {
+	listed := candidates
+	if len(listed) > maxCandidates {
+		listed = listed[:maxCandidates]
+	}
+	return fmt.Errorf("\"%s\" matches %d results: %s", query, len(candidates), strings.Join(listed, ", "))
+}
+
+// searchMembers
+// Search the members of this guild whose username, display name or nickname starts with the query
+// discordgo doesn't decode display names (global_name) yet, so they are decoded here, and returned keyed by user ID
+func (g *Guild) searchMembers(query string) ([]*discordgo.Member, map[string]string, error) {
+	uri := discordgo.EndpointGuildMembersSearch(g.ID)
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("limit", strconv.Itoa(memberSearchLimit))
+
+	body, err := Session.RequestWithBucketID("GET", uri+"?"+params.Encode(), nil, uri)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var members []*discordgo.Member
+	if err = json.Unmarshal(body, &members); err != nil {
+		return nil, nil, err
+	}
+	var users []struct {
+		User struct {
+			ID         string `json:"id"`
+			GlobalName string `json:"global_name"`
+		} `json:"user"`
+	}
+	if err = json.Unmarshal(body, &users); err != nil {
+		return nil, nil, err
+	}
+
+	globalNames := make(map[string]string, len(users))
+	for _, member := range users {
+		if member.User.GlobalName != "" {
+			globalNames[member.User.ID] = member.User.GlobalName
+		}
+	}
+	return members, globalNames, nil
+}
+
+// memberMatchPasses
+// The ways a name is matched against the names of members, from most to least specific
+// The first pass that matches any member decides the result
+var memberMatchPasses = []func(memberName string, name string) bool{
+	strings.EqualFold,
+	func(memberName string, name string) bool {
+		return strings.HasPrefix(strings.ToLower(memberName), strings.ToLower(name))
+	},
+	func(memberName string, name string) bool {
+		return strings.Contains(strings.ToLower(memberName), strings.ToLower(name))
+	},
+}
+
+// FindMember
+// Find a member of this guild by ID, mention, username (or the legacy username#discriminator), display name, or nickname
+// Exact username matches win, then case-insensitive matches of the whole name, then names starting with, and finally containing, the query
+// Returns an error listing the candidates if more than one member matches
+func (g *Guild) FindMember(query string) (*discordgo.Member, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("no member was given")
+	}
+
+	if isIdOrMention(query, MentionStringRegexes["user"]) {
+		return g.GetMember(query)
+	}
+
+	name := strings.TrimPrefix(query, "@")
+	username, discriminator, hasDiscriminator := strings.Cut(name, "#")
+
+	// Discord's search matches the start of usernames, display names and nicknames, so it narrows down the candidates for us
+	members, globalNames, err := g.searchMembers(username)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*discordgo.Member
+	for _, member := range members {
+		if member.User == nil {
+			continue
+		}
+
+		if hasDiscriminator {
+			if strings.EqualFold(member.User.Username, username) && member.User.Discriminator == discriminator {
+				matches = append(matches, member)
+			}
+		} else if member.User.Username == name {
+			matches = append(matches, member)
+		}
+	}
+
+	for _, matchPass := range memberMatchPasses {
+		if len(matches) != 0 || hasDiscriminator {
+			break
+		}
+		for _, member := range members {
+			if member.User == nil {
+				continue
+			}
+			for _, memberName := range []string{member.User.Username, globalNames[member.User.ID], member.Nick} {
+				if memberName != "" && matchPass(memberName, name) {
+					matches = append(matches, member)
+					break
+				}
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.New("member not found")
+	case 1:
+		return matches[0], nil
+	default:
+		candidates := make([]string, 0, len(matches))
+		for _, member := range matches {
+			candidates = append(candidates, member.User.Username+" ("+member.User.ID+")")
+		}
+		return nil, ambiguousError(query, candidates)
+	}
+}
+
+// FindChannel
+// Find a channel of this guild by ID, mention, or name (with or without a leading "#")
+// Names are matched case-insensitively, but an exact match wins if there is one
+// Returns an error listing the candidates if more than one channel matches
+func (g *Guild) FindChannel(query string) (*discordgo.Channel, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("no channel was given")
+	}
+
+	if isIdOrMention(query, MentionStringRegexes["channel"]) {
+		return g.GetChannel(query)
+	}
+
+	channels, err := Session.GuildChannels(g.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimPrefix(query, "#")
+	var exact, loose []*discordgo.Channel
+	for _, channel := range channels {
+		if channel.Name == name {
+			exact = append(exact, channel)
+		} else if strings.EqualFold(channel.Name, name) {
+			loose = append(loose, channel)
+		}
+	}
+
+	matches := exact
+	if len(matches) == 0 {
+		matches = loose
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.New("channel not found")
+	case 1:
+		return matches[0], nil
+	default:
+		candidates := make([]string, 0, len(matches))
+		for _, channel := range matches {
+			candidates = append(candidates, "#"+channel.Name+" ("+channel.ID+")")
+		}
+		return nil, ambiguousError(query, candidates)
+	}
+}
+
+// FindRole
+// Find a role of this guild by ID, mention, or name (with or without a leading "@" or "@&")
+// Names are matched case-insensitively, but an exact match wins if there is one
+// Returns an error listing the candidates if more than one role matches
+func (g *Guild) FindRole(query string) (*discordgo.Role, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("no role was given")
+	}
+
+	if isIdOrMention(query, MentionStringRegexes["role"]) {
+		return g.GetRole(query)
+	}
+
+	roles, err := Session.GuildRoles(g.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(query, "@"), "&")
+	var exact, loose []*discordgo.Role
+	for _, role := range roles {
+		if role.Name == name {
+			exact = append(exact, role)
+		} else if strings.EqualFold(role.Name, name) {
+			loose = append(loose, role)
+		}
+	}
+
+	matches := exact
+	if len(matches) == 0 {
+		matches = loose
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.New("role not found")
+	case 1:
+		return matches[0], nil
+	default:
+		candidates := make([]string, 0, len(matches))
+		for _, role := range matches {
+			candidates = append(candidates, "@"+role.Name+" ("+role.ID+")")
+		}
+		return nil, ambiguousError(query, candidates)
+	}
+}
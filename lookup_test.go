@@ -0,0 +1,53 @@
+package framework
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFindMember(t *testing.T) {
+	useFakeSession(t, func(req *http.Request) (int, string) {
+		if !strings.HasSuffix(req.URL.Path, "/members/search") {
+			return http.StatusNotFound, `{"code":10013,"message":"Unknown User"}`
+		}
+		return http.StatusOK, `[
+			{"user":{"id":"700000000000000001","username":"alice","global_name":"Wonderland Alice"}},
+			{"user":{"id":"700000000000000002","username":"Alice","global_name":null},"nick":"Boss"},
+			{"user":{"id":"700000000000000003","username":"bob_1234","global_name":"Bobby Tables"}},
+			{"user":{"id":"700000000000000004","username":"carol","global_name":"Carol"},"nick":"Bobcat"}
+		]`
+	})
+	g := &Guild{ID: "700000000000000000"}
+
+	tests := []struct {
+		query string
+		want  string // the ID of the member, or blank if an error is expected
+	}{
+		{"alice", "700000000000000001"},
+		{"Alice", "700000000000000002"},
+		{"@boss", "700000000000000002"},
+		{"wonderland alice", "700000000000000001"},
+		{"bobby tables", "700000000000000003"},
+		{"Bobby", "700000000000000003"},
+		{"Tables", "700000000000000003"},
+		{"bob", ""},   // bob_1234 and the nickname Bobcat both start with "bob"
+		{"ALICE", ""}, // alice and Alice match case-insensitively
+		{"dave", ""},
+	}
+
+	for _, tt := range tests {
+		member, err := g.FindMember(tt.query)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("FindMember(%q) = %s, want an error", tt.query, member.User.ID)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FindMember(%q) returned %s", tt.query, err)
+		} else if member.User.ID != tt.want {
+			t.Errorf("FindMember(%q) = %s, want %s", tt.query, member.User.ID, tt.want)
+		}
+	}
+}
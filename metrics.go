@@ -0,0 +1,143 @@
+package framework
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// metrics.go
+// This file contains command usage metrics, and a hook for exporting them elsewhere
+
+// CommandStat
+// Usage statistics of a single command since the bot started
+type CommandStat struct {
+	Count         int64         // How many times the command was run
+	Failures      int64         // How many of those runs panicked
+	Interactions  int64         // How many of those runs were slash commands
+	TotalDuration time.Duration // The combined run time of every run
+	LastRun       time.Time     // When the command was last run
+}
+
+// AverageDuration
+// Get the average run time of the command
+func (cs CommandStat) AverageDuration() time.Duration {
+	if cs.Count == 0 {
+		return 0
+	}
+	return cs.TotalDuration / time.Duration(cs.Count)
+}
+
+// OnCommandRun
+// An optional hook that is called every time a command finishes running, e.g. to export metrics
+// The duration is measured from dispatching the command until its function returns, and success is false if it panicked
+// The built-in statistics (see GetCommandStats) are always tracked, whether this is set or not
+var OnCommandRun func(name string, duration time.Duration, success bool, interaction bool)
+
+// commandStats
+// The usage statistics of every command that has been run, keyed by trigger
+var commandStats = make(map[string]*CommandStat)
+
+// commandStatsLock
+// Guards commandStats, since commands run concurrently
+var commandStatsLock sync.Mutex
+
+// GetCommandStats
+// Get a copy of the usage statistics of every command that has been run since the bot started
+func GetCommandStats() map[string]CommandStat {
+	commandStatsLock.Lock()
+	defer commandStatsLock.Unlock()
+
+	stats := make(map[string]CommandStat, len(commandStats))
+	for name, stat := range commandStats {
+		stats[name] = *stat
+	}
+	return stats
+}
+
+// recordCommandRun
+// Add a command run to the statistics, and pass it on to the OnCommandRun hook
+func recordCommandRun(name string, duration time.Duration, success bool, interaction bool) {
+	commandStatsLock.Lock()
+	stat, ok := commandStats[name]
+	if !ok {
+		stat = &CommandStat{}
+		commandStats[name] = stat
+	}
+	stat.Count++
+	if !success {
+		stat.Failures++
+	}
+	if interaction {
+		stat.Interactions++
+	}
+	stat.TotalDuration += duration
+	stat.LastRun = time.Now()
+	commandStatsLock.Unlock()
+
+	if OnCommandRun != nil {
+		OnCommandRun(name, duration, success, interaction)
+	}
+}
+
+// measureCommand
+// Run a command, and record how long it took and whether it succeeded
+// A panic is recorded as a failure, then left to propagate to the caller's recovery handler
+func measureCommand(name string, interaction bool, run func()) {
+	start := time.Now()
+	success := false
+	defer func() {
+		recordCommandRun(name, time.Since(start), success, interaction)
+	}()
+
+	run()
+	success = true
+}
+
+// -- Per-Guild Usage --
+
+// trackUsage
+// Whether command invocations are counted per guild, in the guild's storage
+var trackUsage = false
+
+// usageKeyPrefix
+// The prefix of the storage keys that hold the invocation count of each command
+const usageKeyPrefix = "command_usage_"
+
+// SetTrackUsage
+// Set whether command invocations are counted per guild, e.g. for a usage leaderboard. Off by default
+// Counts are kept by the lowercase trigger of the top-level command, so prefix and slash invocations add up
+func SetTrackUsage(track bool) {
+	trackUsage = track
+}
+
+// recordGuildUsage
+// Count a command invocation in the guild's storage, if usage tracking is on
+// Invocations outside a guild are not counted
+func recordGuildUsage(g *Guild, trigger string) {
+	if !trackUsage || g == nil || g.ID == "" {
+		return
+	}
+	if _, err := g.IncrementInt64(usageKeyPrefix+strings.ToLower(trigger), 1); err != nil {
+		log.Errorf("Failed to count usage of command %s in guild %s: %s", trigger, g.ID, err)
+	}
+}
+
+// CommandUsage
+// Get how many times each command was used in this guild, keyed by lowercase trigger
+// Commands are only counted while usage tracking is on, see SetTrackUsage
+func (g *Guild) CommandUsage() map[string]int64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	usage := make(map[string]int64)
+	for key, raw := range g.Info.Storage {
+		if !strings.HasPrefix(key, usageKeyPrefix) {
+			continue
+		}
+		if count, err := storageInt64(raw); err == nil {
+			usage[strings.TrimPrefix(key, usageKeyPrefix)] = count
+		}
+	}
+	return usage
+}
@@ -0,0 +1,41 @@
+package framework
+
+import "sync"
+
+// middleware.go
+// This file contains global command middleware, which wraps every command to add cross-cutting behavior
+
+// Middleware
+// A function that wraps a command function, e.g. for logging, permission checks or feature flags
+// A middleware can block the command by returning without calling next
+type Middleware func(next BotFunction) BotFunction
+
+// middlewares
+// The registered middleware, in the order it was registered
+var middlewares []Middleware
+
+// middlewaresLock
+// Guards middlewares, since middleware may be registered while commands are running
+var middlewaresLock sync.RWMutex
+
+// Use
+// Register a middleware that every command (text, slash and sub commands) is run through
+// Middleware runs in the order it was registered, so the first registered middleware is the outermost
+func Use(mw Middleware) {
+	middlewaresLock.Lock()
+	defer middlewaresLock.Unlock()
+	middlewares = append(middlewares, mw)
+}
+
+// applyMiddleware
+// Wrap a command function in every registered middleware
+func applyMiddleware(fn BotFunction) BotFunction {
+	middlewaresLock.RLock()
+	defer middlewaresLock.RUnlock()
+
+	// Wrap from the inside out, so the first registered middleware runs first
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		fn = middlewares[i](fn)
+	}
+	return fn
+}
@@ -0,0 +1,98 @@
+package framework
+
+import (
+	"fmt"
+	"sync"
+)
+
+// migrations.go
+// This file contains schema migrations, which upgrade guild data that was saved by an older version of the bot
+
+// migrations
+// A map of schema versions to the function that upgrades guild data from that version to the next
+var migrations = map[int]func(info *GuildInfo){
+	// Version 0 is data saved before schema versions existed
+	0: func(info *GuildInfo) {
+		if info.Prefix == "" {
+			info.Prefix = "!"
+		}
+		if info.Storage == nil {
+			info.Storage = make(map[string]interface{})
+		}
+	},
+}
+
+// migrationsLock
+// Guards migrations
+var migrationsLock sync.RWMutex
+
+// RegisterMigration
+// Register a function that upgrades guild data from a schema version to the next one, e.g. to fill in a new field
+// Migrations have to be registered before Start, and run in order starting from the version the data was saved with
+// Versions must be registered in order without gaps, since the chain stops at the first version without a migration
+// Registering a version that would leave a gap panics. Registering a version a second time replaces the previous migration
+func RegisterMigration(fromVersion int, fn func(info *GuildInfo)) {
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+
+	// The next version to register is the latest version, which doesn't have a migration yet
+	next := latestSchemaVersionLocked()
+	if fromVersion < 0 || fromVersion > next {
+		panic(fmt.Sprintf("migration from schema version %d can't be registered; the next version to register is %d", fromVersion, next))
+	}
+	migrations[fromVersion] = fn
+}
+
+// latestSchemaVersion
+// Get the schema version that new guild data is saved with
+func latestSchemaVersion() int {
+	migrationsLock.RLock()
+	defer migrationsLock.RUnlock()
+	return latestSchemaVersionLocked()
+}
+
+// latestSchemaVersionLocked
+// Like latestSchemaVersion, for when migrationsLock is already held
+func latestSchemaVersionLocked() int {
+	latest := 0
+	for version := range migrations {
+		if version+1 > latest {
+			latest = version + 1
+		}
+	}
+	return latest
+}
+
+// migrateGuildInfo
+// Run every migration the guild data needs to reach the latest schema version, in order
+// Returns whether the data was changed, and therefore needs to be saved
+func migrateGuildInfo(guildId string, info *GuildInfo) bool {
+	migrationsLock.RLock()
+	defer migrationsLock.RUnlock()
+
+	from := info.SchemaVersion
+	for {
+		migrate, ok := migrations[info.SchemaVersion]
+		if !ok {
+			break
+		}
+		migrate(info)
+		info.SchemaVersion++
+	}
+
+	if info.SchemaVersion == from {
+		return false
+	}
+	log.Infof("Migrated guild %s from schema version %d to %d", guildId, from, info.SchemaVersion)
+	return true
+}
+
+// migrateGuilds
+// Migrate every loaded guild to the latest schema version, and save the ones that changed
+func migrateGuilds(guilds map[string]*Guild) {
+	for id, guild := range guilds {
+		if migrateGuildInfo(id, &guild.Info) {
+			_ = guild.save()
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package framework
+
+import "testing"
+
+func TestRegisterMigrationRejectsGaps(t *testing.T) {
+	migrationsLock.Lock()
+	oldMigrations := migrations
+	migrations = map[int]func(info *GuildInfo){0: oldMigrations[0]}
+	migrationsLock.Unlock()
+	t.Cleanup(func() {
+		migrationsLock.Lock()
+		migrations = oldMigrations
+		migrationsLock.Unlock()
+	})
+
+	registers := func(fromVersion int) (ok bool) {
+		defer func() {
+			if recover() != nil {
+				ok = false
+			}
+		}()
+		RegisterMigration(fromVersion, func(info *GuildInfo) { info.Locale = "migrated" })
+		return true
+	}
+
+	tests := []struct {
+		fromVersion int
+		want        bool
+	}{
+		{2, false},
+		{-1, false},
+		{1, true},
+		{1, true}, // replacing a migration
+		{0, true},
+		{3, false},
+		{2, true},
+	}
+	for _, tt := range tests {
+		if got := registers(tt.fromVersion); got != tt.want {
+			t.Errorf("RegisterMigration(%d) succeeded = %v, want %v", tt.fromVersion, got, tt.want)
+		}
+	}
+
+	info := GuildInfo{}
+	migrateGuildInfo("300000000000000006", &info)
+	if info.SchemaVersion != latestSchemaVersion() || latestSchemaVersion() != 3 {
+		t.Errorf("migrated to schema version %d, latest is %d; want 3", info.SchemaVersion, latestSchemaVersion())
+	}
+}
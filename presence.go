@@ -0,0 +1,92 @@
+package framework
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// presence.go
+// This file contains the bot's presence (status and activity), and rotating through several activities
+
+// botPresence
+// The presence of the bot, or nil if none was set
+var botPresence *discordgo.UpdateStatusData
+
+// presenceLock
+// Guards botPresence, since the presence can be changed while the bot is running
+var presenceLock sync.Mutex
+
+// SetPresence
+// Set the status (e.g. discordgo.StatusOnline) and activity (e.g. "Playing !help") of the bot
+// activity may be nil to show no activity
+// This can be called before Start, in which case the presence is applied once the session is ready
+func SetPresence(status discordgo.Status, activity *discordgo.Activity) {
+	presence := &discordgo.UpdateStatusData{
+		Status:     string(status),
+		Activities: []*discordgo.Activity{},
+	}
+	if activity != nil {
+		presence.Activities = append(presence.Activities, activity)
+	}
+
+	presenceLock.Lock()
+	botPresence = presence
+	presenceLock.Unlock()
+
+	// If the sessions are already ready, update the presence right away
+	for _, shard := range Sessions {
+		shard.RLock()
+		ready := shard.DataReady
+		shard.RUnlock()
+		if ready {
+			applyPresence(shard)
+		}
+	}
+}
+
+// RotatePresence
+// Cycle through the given activities, switching to the next one every interval
+// The status set with SetPresence is kept, defaulting to online. This must be called before Start, since it adds a worker
+func RotatePresence(activities []discordgo.Activity, interval time.Duration) {
+	if len(activities) == 0 {
+		return
+	}
+
+	next := 0
+	AddWorker(func(ctx context.Context) {
+		status := discordgo.StatusOnline
+		presenceLock.Lock()
+		if botPresence != nil && botPresence.Status != "" {
+			status = discordgo.Status(botPresence.Status)
+		}
+		presenceLock.Unlock()
+
+		activity := activities[next]
+		SetPresence(status, &activity)
+		next = (next + 1) % len(activities)
+	}, interval)
+}
+
+// applyPresence
+// Send the bot's presence to Discord through the session of a shard, if one was set
+// Presence is per shard, so every shard has to send it
+func applyPresence(shard *discordgo.Session) {
+	presenceLock.Lock()
+	defer presenceLock.Unlock()
+	if botPresence == nil {
+		return
+	}
+
+	if err := shard.UpdateStatusComplex(*botPresence); err != nil {
+		log.Errorf("Failed to update the bot's presence: %s", err)
+	}
+}
+
+// presenceReadyHandler
+// Apply the bot's presence whenever the session becomes ready, including after reconnecting
+func presenceReadyHandler(s *discordgo.Session, r *discordgo.Ready) {
+	applyPresence(s)
+}
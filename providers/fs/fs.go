@@ -1,14 +1,10 @@
-//go:build darwin || linux
-// +build darwin linux
-
 package fs
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/qpixel/framework"
 	tlog "github.com/ubergeek77/tinylog"
-	"golang.org/x/sys/unix"
-	"io/ioutil"
 	"os"
 	"path"
 	"strings"
@@ -17,6 +13,7 @@ import (
 
 // fs.go
 // This file contains functions that pertain to interacting with the filesystem, including mutex locking of files
+// The platform specific parts are in fs_unix.go and fs_windows.go
 
 var log = tlog.NewTaggedLogger("BotCore", tlog.NewColor("38;5;111"))
 
@@ -30,9 +27,16 @@ var GuildsDir = "./guilds"
 // This ensures files are written to synchronously, avoiding file race conditions
 var saveLock = make(map[string]*sync.Mutex)
 
+// loadWorkers
+// How many guild files are read at the same time while loading guilds
+var loadWorkers = 8
+
 // loadGuilds
 // Load all known guilds from the filesystem, from inside GuildsDir
-func loadGuilds() (guilds map[string]*framework.Guild) {
+// The files are read in parallel, so bots with thousands of guilds start quickly
+func loadGuilds() map[string]*framework.Guild {
+	guilds := make(map[string]*framework.Guild)
+
 	// Check if the configured guild directory exists, and create it if otherwise
 	if _, existErr := os.Stat(GuildsDir); os.IsNotExist(existErr) {
 		mkErr := os.MkdirAll(GuildsDir, 0755)
@@ -46,13 +50,38 @@ func loadGuilds() (guilds map[string]*framework.Guild) {
 	}
 
 	// Get a list of files in the directory
-	guilds = make(map[string]*framework.Guild)
-	files, rdErr := ioutil.ReadDir(GuildsDir)
+	files, rdErr := os.ReadDir(GuildsDir)
 	if rdErr != nil {
 		log.Fatalf("Failed to read guild directory: %s", rdErr)
 	}
 
-	// Iterate over each file
+	// Start the workers that read the guild files
+	var guildsLock sync.Mutex
+	var wg sync.WaitGroup
+	guildIds := make(chan string)
+	for i := 0; i < loadWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for guildId := range guildIds {
+				gInfo, err := readGuildFile(guildId)
+				if err != nil {
+					log.Errorf("Guild %s WILL NOT be loaded! (%s)", guildId, err)
+					continue
+				}
+
+				// Add the loaded guild to the map
+				guildsLock.Lock()
+				guilds[guildId] = &framework.Guild{
+					ID:   guildId,
+					Info: *gInfo,
+				}
+				guildsLock.Unlock()
+			}
+		}()
+	}
+
+	// Iterate over each file, and hand the guild files to the workers
 	for _, file := range files {
 		// Ignore directories
 		if file.IsDir() {
@@ -75,35 +104,10 @@ func loadGuilds() (guilds map[string]*framework.Guild) {
 			continue
 		}
 
-		// Even though we are reading files, we need to make sure we can write to this file later
-		fPath := path.Join(GuildsDir, fName)
-		err := unix.Access(fPath, unix.O_RDWR)
-		if err != nil {
-			log.Errorf("File \"%s\" is not writable; guild %s WILL NOT be loaded! (%s)", fPath, guildId, err)
-			continue
-		}
-
-		// Try reading the file
-		jsonBytes, err := ioutil.ReadFile(fPath)
-		if err != nil {
-			log.Errorf("Failed to read \"%s\"; guild %s WILL NOT be loaded! (%s)", fPath, guildId, err)
-			continue
-		}
-
-		// Unmarshal the json
-		var gInfo framework.GuildInfo
-		err = json.Unmarshal(jsonBytes, &gInfo)
-		if err != nil {
-			log.Errorf("Failed to unmarshal \"%s\"; guild %s WILL NOT be loaded! (%s)", fPath, guildId, err)
-			continue
-		}
-
-		// Add the loaded guild to the map
-		guilds[guildId] = &framework.Guild{
-			ID:   guildId,
-			Info: gInfo,
-		}
+		guildIds <- guildId
 	}
+	close(guildIds)
+	wg.Wait()
 
 	if len(guilds) == 0 {
 		log.Warningf("There are no guilds to load; data for new guilds will be saved to \"%s\"", GuildsDir)
@@ -112,7 +116,7 @@ func loadGuilds() (guilds map[string]*framework.Guild) {
 
 	// :)
 	plural := ""
-	if len(framework.Guilds) != 1 {
+	if len(guilds) != 1 {
 		plural = "s"
 	}
 
@@ -120,9 +124,42 @@ func loadGuilds() (guilds map[string]*framework.Guild) {
 	return guilds
 }
 
+// readGuildFile
+// Read and unmarshal the .json file of a guild
+func readGuildFile(guildId string) (*framework.GuildInfo, error) {
+	fPath := path.Join(GuildsDir, guildId+".json")
+
+	// Read-only files are still loaded, but changes to the guild can't be saved
+	if err := checkWritable(fPath); err != nil {
+		log.Warningf("File \"%s\" is not writable; guild %s is loaded read-only, and saving it will fail! (%s)", fPath, guildId, err)
+	}
+
+	// Try reading the file
+	jsonBytes, err := os.ReadFile(fPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read \"%s\": %w", fPath, err)
+	}
+
+	// Unmarshal the json
+	var gInfo framework.GuildInfo
+	err = json.Unmarshal(jsonBytes, &gInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal \"%s\": %w", fPath, err)
+	}
+
+	return &gInfo, nil
+}
+
+// loadGuild
+// Load a single guild from the filesystem, from inside GuildsDir
+func loadGuild(guildId string) (*framework.GuildInfo, error) {
+	return readGuildFile(guildId)
+}
+
 // save
 // Save a given guild object to .json
-func save(g *framework.Guild) {
+// Errors are returned instead of being fatal, so one guild failing to save doesn't take down the bot
+func save(g *framework.Guild) error {
 	// See if a mutex exists for this guild, and create if not
 	if _, ok := saveLock[g.ID]; !ok {
 		saveLock[g.ID] = &sync.Mutex{}
@@ -135,25 +172,44 @@ func save(g *framework.Guild) {
 	saveLock[g.ID].Lock()
 
 	// Create the output directory if it doesn't exist
-	// This is a fatal error, since no other guilds would be savable if this fails
 	if _, err := os.Stat(GuildsDir); os.IsNotExist(err) {
 		mkErr := os.Mkdir(GuildsDir, 0755)
 		if mkErr != nil {
-			log.Fatalf("Failed to create guild output directory: %s", mkErr)
+			return fmt.Errorf("failed to create guild output directory: %w", mkErr)
 		}
 	}
 
 	// Convert the guild object to text
 	jsonBytes, err := json.MarshalIndent(g.Info, "", "    ")
 	if err != nil {
-		log.Fatalf("Failed marshalling JSON data for guild %s: %s", g.ID, err)
+		return fmt.Errorf("failed marshalling JSON data for guild %s: %w", g.ID, err)
 	}
 
 	// Write the contents to a file
 	outPath := path.Join(GuildsDir, g.ID+".json")
-	err = ioutil.WriteFile(outPath, jsonBytes, 0644)
+	err = os.WriteFile(outPath, jsonBytes, 0644)
 	if err != nil {
-		log.Fatalf("Write failed to %s: %s", outPath, err)
+		return fmt.Errorf("write failed to %s: %w", outPath, err)
+	}
+	return nil
+}
+
+// deleteGuild
+// Delete a given guild's .json file
+func deleteGuild(guildId string) {
+	// See if a mutex exists for this guild, and create if not
+	if _, ok := saveLock[guildId]; !ok {
+		saveLock[guildId] = &sync.Mutex{}
+	}
+
+	// Make sure the guild isn't being written to while it is deleted
+	saveLock[guildId].Lock()
+	defer saveLock[guildId].Unlock()
+
+	outPath := path.Join(GuildsDir, guildId+".json")
+	err := os.Remove(outPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Errorf("Failed to delete %s: %s", outPath, err)
 	}
 }
 
@@ -161,7 +217,9 @@ func save(g *framework.Guild) {
 // Inits the filesystem provider
 func InitProvider() framework.GuildProvider {
 	return framework.GuildProvider{
-		Save: save,
-		Load: loadGuilds,
+		Save:    save,
+		Load:    loadGuilds,
+		LoadOne: loadGuild,
+		Delete:  deleteGuild,
 	}
 }
@@ -0,0 +1,84 @@
+package fs
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	"github.com/qpixel/framework"
+)
+
+// writeGuildFiles
+// Point GuildsDir at a temporary directory holding n guild files, and return their IDs
+func writeGuildFiles(tb testing.TB, n int) []string {
+	tb.Helper()
+
+	oldDir := GuildsDir
+	GuildsDir = tb.TempDir()
+	tb.Cleanup(func() { GuildsDir = oldDir })
+
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = strconv.Itoa(100000000000000000 + i)
+		info := framework.GuildInfo{
+			AddedDate:    int64(i),
+			Prefix:       "!",
+			ModeratorIds: []string{"200000000000000000"},
+			Storage:      map[string]interface{}{"counter": float64(i)},
+		}
+		data, err := json.MarshalIndent(info, "", "    ")
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if err = os.WriteFile(path.Join(GuildsDir, ids[i]+".json"), data, 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	// Files that aren't guilds have to be skipped
+	if err := os.WriteFile(path.Join(GuildsDir, "notes.txt"), []byte("not a guild"), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return ids
+}
+
+func TestLoadGuilds(t *testing.T) {
+	ids := writeGuildFiles(t, 50)
+
+	guilds := loadGuilds()
+	if len(guilds) != len(ids) {
+		t.Fatalf("loaded %d guilds, want %d", len(guilds), len(ids))
+	}
+	for i, id := range ids {
+		g, ok := guilds[id]
+		if !ok {
+			t.Fatalf("guild %s was not loaded", id)
+		}
+		if g.ID != id || g.Info.AddedDate != int64(i) || g.Info.Prefix != "!" {
+			t.Errorf("guild %s was loaded as %+v", id, g.Info)
+		}
+	}
+}
+
+func BenchmarkLoadGuilds(b *testing.B) {
+	defaultWorkers := loadWorkers
+	b.Cleanup(func() { loadWorkers = defaultWorkers })
+
+	for _, n := range []int{100, 1000} {
+		writeGuildFiles(b, n)
+
+		// A single worker reads the files one at a time, like guilds were loaded before
+		for _, workers := range []int{1, defaultWorkers} {
+			b.Run(strconv.Itoa(n)+"guilds/"+strconv.Itoa(workers)+"workers", func(b *testing.B) {
+				loadWorkers = workers
+				for i := 0; i < b.N; i++ {
+					if guilds := loadGuilds(); len(guilds) != n {
+						b.Fatalf("loaded %d guilds, want %d", len(guilds), n)
+					}
+				}
+			})
+		}
+	}
+}
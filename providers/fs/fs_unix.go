@@ -0,0 +1,15 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package fs
+
+import "golang.org/x/sys/unix"
+
+// fs_unix.go
+// This file contains the filesystem functions that are specific to unix-like systems
+
+// checkWritable
+// Check that the bot can write to a file
+func checkWritable(fPath string) error {
+	return unix.Access(fPath, unix.O_RDWR)
+}
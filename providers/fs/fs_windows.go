@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package fs
+
+import "golang.org/x/sys/windows"
+
+// fs_windows.go
+// This file contains the filesystem functions that are specific to Windows
+
+// checkWritable
+// Check that the bot can write to a file
+func checkWritable(fPath string) error {
+	fd, err := windows.Open(fPath, windows.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	// Close the file handle, since we are not writing to it yet
+	return windows.Close(fd)
+}
@@ -1,7 +1,11 @@
 package framework
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -25,29 +29,87 @@ type Response struct {
 	Loading            bool
 	Ephemeral          bool
 	Reply              bool
-	Embed              *discordgo.MessageEmbed
+	Embed              *discordgo.MessageEmbed   // The primary embed, which Send fills out
+	Embeds             []*discordgo.MessageEmbed // Any additional embeds, which are sent after the primary embed
 	ResponseComponents *ResponseComponents
+
+	// continuations are the embeds in Embeds that hold the fields that didn't fit in the primary embed
+	continuations []*discordgo.MessageEmbed
+	// allowedMentions are the mentions the response may ping, set with AllowMentions
+	allowedMentions *discordgo.MessageAllowedMentions
 }
 
+// Embed limits
+// Discord rejects messages with embeds that exceed any of these limits
+const (
+	maxEmbedFields      = 25
+	maxEmbedTitle       = 256
+	maxEmbedDescription = 4096
+	maxFieldName        = 256
+	maxFieldValue       = 1024
+	maxAuthorName       = 256
+	maxEmbedsLength     = 6000 // The combined length of the text in all embeds of a message
+	maxContent          = 2000 // The length of the plain text content of a message
+)
+
 // CreateField
 // Create message field to use for an embed
+// Names and values that are too long for Discord are truncated
 func CreateField(name string, value string, inline bool) *discordgo.MessageEmbedField {
 	return &discordgo.MessageEmbedField{
-		Name:   name,
-		Value:  value,
+		Name:   Truncate(name, maxFieldName),
+		Value:  Truncate(value, maxFieldValue),
 		Inline: inline,
 	}
 }
 
+// defaultFooter
+// The footer stamped on every embed created with CreateEmbed, nil for no footer
+var defaultFooter *discordgo.MessageEmbedFooter
+
+// embedTimestamp
+// Whether every embed created with CreateEmbed is stamped with the time it was created
+var embedTimestamp = false
+
+// SetDefaultFooter
+// Set the footer stamped on every embed created with CreateEmbed, e.g. to show the bot version
+// An empty text removes the default footer. iconURL may be empty
+func SetDefaultFooter(text string, iconURL string) {
+	if text == "" {
+		defaultFooter = nil
+		return
+	}
+	defaultFooter = &discordgo.MessageEmbedFooter{
+		Text:    text,
+		IconURL: iconURL,
+	}
+}
+
+// SetEmbedTimestamp
+// Set whether every embed created with CreateEmbed is stamped with the time it was created
+func SetEmbedTimestamp(enabled bool) {
+	embedTimestamp = enabled
+}
+
 // CreateEmbed
 // Create an embed
+// The embed gets the default footer and timestamp, if they are enabled
 func CreateEmbed(color int, title string, description string, fields []*discordgo.MessageEmbedField) *discordgo.MessageEmbed {
-	return &discordgo.MessageEmbed{
+	embed := &discordgo.MessageEmbed{
 		Title:       title,
 		Description: description,
 		Color:       color,
 		Fields:      fields,
 	}
+	if defaultFooter != nil {
+		// Copy the footer, so changing the footer of one embed doesn't change it for every embed
+		footer := *defaultFooter
+		embed.Footer = &footer
+	}
+	if embedTimestamp {
+		embed.Timestamp = time.Now().Format(time.RFC3339)
+	}
+	return embed
 }
 
 // CreateComponentFields
@@ -70,7 +132,7 @@ func NewResponse(ctx *Context, messageComponents bool, ephemeral bool) *Response
 			Components:        nil,
 			SelectMenuOptions: nil,
 		},
-		Loading:   ctx.Cmd.IsTyping,
+		Loading:   ctx.deferred, // A deferred interaction has to be answered by editing the deferred response
 		Ephemeral: ephemeral,
 		Reply:     ephemeral,
 	}
@@ -78,50 +140,282 @@ func NewResponse(ctx *Context, messageComponents bool, ephemeral bool) *Response
 		r.ResponseComponents.Components = CreateComponentFields()
 		r.ResponseComponents.SelectMenuOptions = []discordgo.SelectMenuOption{}
 	}
-	if r.Loading && ctx.Interaction != nil {
-		if ephemeral {
-			_ = Session.InteractionRespond(r.Ctx.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					// Ephemeral is type 64 don't ask why
-					Flags: 1 << 6,
-				},
-			})
-		} else {
-			_ = Session.InteractionRespond(r.Ctx.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-			})
+
+	return r
+}
+
+// ReconstructResponse
+// Rebuild a response from the message a component is attached to, e.g. to update it from a component handler
+// All the embeds and components of the message are kept. A message without embeds gets an empty primary embed
+func ReconstructResponse(ctx *Context, ephemeral bool) (*Response, error) {
+	if ctx.Interaction == nil || ctx.Interaction.Message == nil {
+		return nil, errors.New("there is no message to reconstruct the response from")
+	}
+	message := ctx.Interaction.Message
+
+	r := &Response{
+		Ctx:   ctx,
+		Embed: CreateEmbed(0, "", "", nil),
+		ResponseComponents: &ResponseComponents{
+			Components:        message.Components,
+			SelectMenuOptions: nil,
+		},
+		Ephemeral: ephemeral,
+		Reply:     ephemeral,
+	}
+	if len(message.Embeds) > 0 {
+		r.Embed = message.Embeds[0]
+		r.Embeds = message.Embeds[1:]
+	}
+
+	return r, nil
+}
+
+// maxEmbeds
+// The most embeds Discord allows in a single message
+const maxEmbeds = 10
+
+// AddEmbed
+// Add an embed to the response, which is sent after the primary embed and any embeds added before it
+// Errors if the message would have more embeds than Discord allows
+func (r *Response) AddEmbed(embed *discordgo.MessageEmbed) error {
+	if len(r.allEmbeds()) >= maxEmbeds {
+		return fmt.Errorf("a response can have at most %d embeds", maxEmbeds)
+	}
+	r.Embeds = append(r.Embeds, embed)
+	return nil
+}
+
+// allEmbeds
+// Get the primary embed followed by any additional embeds, in the order they are sent
+func (r *Response) allEmbeds() []*discordgo.MessageEmbed {
+	return append([]*discordgo.MessageEmbed{r.Embed}, r.Embeds...)
+}
+
+// Edit
+// Update the message of an interaction with the current embeds and components of the response
+// For a component interaction this is the message the component is attached to, otherwise it is the interaction's response
+func (r *Response) Edit() error {
+	if r.Ctx.Interaction == nil {
+		return errors.New("only interaction responses can be edited")
+	}
+
+	if r.Ctx.Interaction.Type == discordgo.InteractionMessageComponent {
+		err := Session.InteractionRespond(r.Ctx.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     r.allEmbeds(),
+				Components: r.ResponseComponents.Components,
+			},
+		})
+		// If the interaction was already responded to, edit the response instead
+		if err == nil {
+			return nil
 		}
 	}
 
-	return r
+	_, err := Session.InteractionResponseEdit(r.Ctx.Interaction, &discordgo.WebhookEdit{
+		Embeds:     ToPtr(r.allEmbeds()),
+		Components: &r.ResponseComponents.Components,
+	})
+	return err
 }
 
 // -- Fields --
 
 // AppendField
 // Create a new basic field and append it to an existing Response
+// If the embed is full, the fields that don't fit are moved into a continuation embed
 func (r *Response) AppendField(name string, value string, inline bool) {
 	r.Embed.Fields = append(r.Embed.Fields, CreateField(name, value, inline))
+	r.spillFields()
 }
 
 // PrependField
 // Create a new basic field and prepend it to an existing Response
+// If the embed is full, the fields that don't fit are moved into a continuation embed
 func (r *Response) PrependField(name string, value string, inline bool) {
 	fields := []*discordgo.MessageEmbedField{CreateField(name, value, inline)}
 	r.Embed.Fields = append(fields, r.Embed.Fields...)
+	r.spillFields()
+}
+
+// spillFields
+// Move the fields past Discord's limit from the primary embed into continuation embeds, keeping their order
+func (r *Response) spillFields() {
+	embeds := append([]*discordgo.MessageEmbed{r.Embed}, r.continuations...)
+	for i := 0; i < len(embeds); i++ {
+		if len(embeds[i].Fields) <= maxEmbedFields {
+			continue
+		}
+		overflow := append([]*discordgo.MessageEmbedField{}, embeds[i].Fields[maxEmbedFields:]...)
+		embeds[i].Fields = embeds[i].Fields[:maxEmbedFields]
+
+		if i+1 == len(embeds) {
+			continuation := CreateEmbed(r.Embed.Color, "", "", nil)
+			if err := r.AddEmbed(continuation); err != nil {
+				log.Warningf("Dropping %d field(s) that don't fit in the response: %s", len(overflow), err)
+				return
+			}
+			r.continuations = append(r.continuations, continuation)
+			embeds = append(embeds, continuation)
+		}
+		embeds[i+1].Fields = append(overflow, embeds[i+1].Fields...)
+	}
+}
+
+// truncatedSuffix
+// Appended to descriptions that were cut off because they were too long
+const truncatedSuffix = "… (truncated)"
+
+// truncateDescription
+// Cut off a description that is too long for Discord, so the response can still be sent
+// Splitting it across embeds wouldn't help much, since all embeds of a message share a 6000 character limit
+func truncateDescription(description string) string {
+	runes := []rune(description)
+	if len(runes) <= maxEmbedDescription {
+		return description
+	}
+
+	log.Warningf("Truncating response description of %d characters to %d", len(runes), maxEmbedDescription)
+	return string(runes[:maxEmbedDescription-utf8.RuneCountInString(truncatedSuffix)]) + truncatedSuffix
+}
+
+// Validate
+// Check that the response is within all of Discord's limits for embeds, so it won't be rejected when it is sent
+func (r *Response) Validate() error {
+	embeds := r.allEmbeds()
+	if len(embeds) > maxEmbeds {
+		return fmt.Errorf("response has %d embeds, but at most %d are allowed", len(embeds), maxEmbeds)
+	}
+
+	total := 0
+	for i, embed := range embeds {
+		if len(embed.Fields) > maxEmbedFields {
+			return fmt.Errorf("embed %d has %d fields, but at most %d are allowed", i, len(embed.Fields), maxEmbedFields)
+		}
+		if n := utf8.RuneCountInString(embed.Title); n > maxEmbedTitle {
+			return fmt.Errorf("embed %d has a title of %d characters, but at most %d are allowed", i, n, maxEmbedTitle)
+		}
+		if n := utf8.RuneCountInString(embed.Description); n > maxEmbedDescription {
+			return fmt.Errorf("embed %d has a description of %d characters, but at most %d are allowed", i, n, maxEmbedDescription)
+		}
+		total += utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+
+		for j, field := range embed.Fields {
+			if n := utf8.RuneCountInString(field.Name); n > maxFieldName {
+				return fmt.Errorf("field %d of embed %d has a name of %d characters, but at most %d are allowed", j, i, n, maxFieldName)
+			}
+			if n := utf8.RuneCountInString(field.Value); n > maxFieldValue {
+				return fmt.Errorf("field %d of embed %d has a value of %d characters, but at most %d are allowed", j, i, n, maxFieldValue)
+			}
+			total += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+		}
+		if embed.Footer != nil {
+			total += utf8.RuneCountInString(embed.Footer.Text)
+		}
+		if embed.Author != nil {
+			total += utf8.RuneCountInString(embed.Author.Name)
+		}
+	}
+
+	if total > maxEmbedsLength {
+		return fmt.Errorf("response has %d characters in its embeds, but at most %d are allowed", total, maxEmbedsLength)
+	}
+	return nil
+}
+
+// SetFooter
+// Set the footer of the response, replacing the default footer
+// An empty text removes the footer, so individual commands can opt out of the default footer
+func (r *Response) SetFooter(text string, iconURL string) {
+	if text == "" {
+		r.Embed.Footer = nil
+		return
+	}
+	r.Embed.Footer = &discordgo.MessageEmbedFooter{
+		Text:    text,
+		IconURL: iconURL,
+	}
+}
+
+// validEmbedURL
+// Check that a URL can be used in an embed. Discord only accepts http(s) URLs
+// Invalid URLs are logged, so the embed can still be sent without them
+func validEmbedURL(kind string, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Warningf("Ignoring invalid embed %s URL %q: only http(s) URLs are allowed", kind, rawURL)
+		return false
+	}
+	return true
+}
+
+// SetThumbnail
+// Set the thumbnail of the response, shown in the top right corner of the embed
+func (r *Response) SetThumbnail(thumbnailURL string) *Response {
+	if validEmbedURL("thumbnail", thumbnailURL) {
+		r.Embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: thumbnailURL}
+	}
+	return r
+}
+
+// SetImage
+// Set the image of the response, shown at the bottom of the embed
+func (r *Response) SetImage(imageURL string) *Response {
+	if validEmbedURL("image", imageURL) {
+		r.Embed.Image = &discordgo.MessageEmbedImage{URL: imageURL}
+	}
+	return r
+}
+
+// SetAuthor
+// Set the author of the response, shown above the title of the embed
+// iconURL and authorURL are optional and may be empty
+func (r *Response) SetAuthor(name string, iconURL string, authorURL string) *Response {
+	author := &discordgo.MessageEmbedAuthor{Name: Truncate(name, maxAuthorName)}
+	if iconURL != "" && validEmbedURL("author icon", iconURL) {
+		author.IconURL = iconURL
+	}
+	if authorURL != "" && validEmbedURL("author", authorURL) {
+		author.URL = authorURL
+	}
+	r.Embed.Author = author
+	return r
+}
+
+// SetURL
+// Set the URL of the response, which the title of the embed links to
+func (r *Response) SetURL(embedURL string) *Response {
+	if validEmbedURL("title", embedURL) {
+		r.Embed.URL = embedURL
+	}
+	return r
 }
 
 // AppendUsage
 // Add the command usage to the response. Intended for syntax error responses
 func (r *Response) AppendUsage() {
+	// Component handlers and the like aren't commands, so there is no usage to show
+	if r.Ctx.Cmd.Trigger == "" {
+		return
+	}
+
 	if r.Ctx.Cmd.Description == "" {
 		r.AppendField("Command description:", "no description", false)
-		return
+	} else {
+		r.AppendField("Command description:", r.Ctx.Cmd.Description, false)
 	}
-	r.AppendField("Command description:", r.Ctx.Cmd.Description, false)
-	//r.AppendField("Command usage:", r.Ctx.Guild.GetCommandUsage(r.Ctx.Cmd), false)
 
+	// Slash commands are always invoked with "/", and outside a guild there is no prefix to show
+	switch {
+	case r.Ctx.Interaction != nil:
+		r.AppendField("Command usage:", commandUsage("/", r.Ctx.Cmd), false)
+	case r.Ctx.Guild == nil:
+		r.AppendField("Command usage:", commandUsage("", r.Ctx.Cmd), false)
+	default:
+		r.AppendField("Command usage:", r.Ctx.Guild.GetCommandUsage(r.Ctx.Cmd), false)
+	}
 }
 
 // -- Message Components --
@@ -176,46 +470,252 @@ func (r *Response) AppendDropDown(customID string, placeholder string, noNewRow
 	}
 }
 
+// guildId
+// Get the ID of the guild this response is for, or a blank string if there is no guild (DMs and user-installed apps)
+func (r *Response) guildId() string {
+	if r.Ctx.Guild == nil {
+		return ""
+	}
+	return r.Ctx.Guild.ID
+}
+
+// responseChannelId
+// Get the configured response channel for the command's group, or a blank string if there is no guild
+func (r *Response) responseChannelId() string {
+	if r.Ctx.Guild == nil {
+		return ""
+	}
+	return r.Ctx.Guild.GetResponseChannel(r.Ctx.Cmd.Group)
+}
+
+// authorId
+// Get the ID of the user who invoked the command, or a blank string if it is unknown
+func (r *Response) authorId() string {
+	if author := r.Ctx.Author(); author != nil {
+		return author.ID
+	}
+	return ""
+}
+
 // Send
 // Send a compiled response
 func (r *Response) Send(success bool, title string, description string) {
-	// Determine what color to use based on the success state
+	// Determine what color to use based on the success state, using the guild's colors if it has configured them
 	var color int
 	if success {
-		color = ColorSuccess
+		color = r.Ctx.Guild.GetSuccessColor()
 	} else {
 		// On failure, also append the command usage
 		r.AppendUsage()
-		color = ColorFailure
+		color = r.Ctx.Guild.GetFailureColor()
 	}
 
 	// Fill out the main embed
-	r.Embed.Title = title
-	r.Embed.Description = description
+	r.Embed.Title = Truncate(title, maxEmbedTitle)
+	r.Embed.Description = truncateDescription(description)
+	r.SetColor(color)
+
+	r.deliver()
+}
+
+// SendRaw
+// Send the response exactly as it was built, without changing the title, description, or color, or appending the usage
+// Use SetColor to choose the color of the embed
+func (r *Response) SendRaw() {
+	r.deliver()
+}
+
+// noMentions
+// The mentions responses are allowed to ping by default, which is none at all
+func noMentions() *discordgo.MessageAllowedMentions {
+	return &discordgo.MessageAllowedMentions{
+		Parse: []discordgo.AllowedMentionType{},
+	}
+}
+
+// AllowMentions
+// Let the response ping the given types of mentions (users, roles, and/or everyone)
+// By default responses don't ping anyone, and SendContent only pings users, so user input can't be used to mass-ping a guild
+func (r *Response) AllowMentions(types ...discordgo.AllowedMentionType) *Response {
+	r.allowedMentions = &discordgo.MessageAllowedMentions{
+		Parse: types,
+	}
+	return r
+}
+
+// mentions
+// Get the mentions the response may ping, or the fallback if the command didn't call AllowMentions
+func (r *Response) mentions(fallback *discordgo.MessageAllowedMentions) *discordgo.MessageAllowedMentions {
+	if r.allowedMentions != nil {
+		return r.allowedMentions
+	}
+	return fallback
+}
+
+// contentMentions
+// The mentions plain text responses are allowed to ping
+// Users can be pinged, but @everyone, @here and roles can't, so pasted user input can't mass-ping a guild
+func contentMentions() *discordgo.MessageAllowedMentions {
+	return &discordgo.MessageAllowedMentions{
+		Parse: []discordgo.AllowedMentionType{discordgo.AllowedMentionTypeUsers},
+	}
+}
+
+// SendContent
+// Send a plain text message instead of the embed, e.g. to ping a user, which mentions in embeds don't do
+// Follows the same routing as Send: interactions are answered (ephemerally if requested), otherwise the
+// response channel is used, falling back to the channel the command was invoked from
+// Components are sent along with the content, the embeds are not
+func (r *Response) SendContent(content string) {
+	content = Truncate(content, maxContent)
+
+	// If guild is nil and this isn't an interaction, this is intended to be sent to Bot Admins
+	if r.Ctx.Guild == nil && r.Ctx.Interaction == nil {
+		for admin := range botAdmins {
+			dmChannel, err := Session.UserChannelCreate(admin)
+			if err == nil {
+				_, err = Session.ChannelMessageSendComplex(dmChannel.ID, &discordgo.MessageSend{
+					Content:         content,
+					Components:      r.ResponseComponents.Components,
+					AllowedMentions: r.mentions(contentMentions()),
+				})
+			}
+			if err != nil {
+				// Since error reports also use DMs, sending this as an error report would be redundant
+				log.Errorf("Failed sending Response DM to admin: %s; %s", admin, err)
+			}
+		}
+		return
+	}
+
+	if r.Ctx.Interaction != nil {
+		var err error
+		if r.Loading && r.publicDeferral() {
+			err = r.sendEphemeralFollowup(&discordgo.WebhookParams{
+				Content:         content,
+				Components:      r.ResponseComponents.Components,
+				AllowedMentions: r.mentions(contentMentions()),
+			})
+		} else if r.Loading {
+			// The interaction was already deferred, so the deferred response has to be edited
+			_, err = Session.InteractionResponseEdit(r.Ctx.Interaction, &discordgo.WebhookEdit{
+				Content:         &content,
+				Embeds:          &[]*discordgo.MessageEmbed{},
+				Components:      &r.ResponseComponents.Components,
+				AllowedMentions: r.mentions(contentMentions()),
+			})
+			r.Loading = false
+		} else {
+			data := &discordgo.InteractionResponseData{
+				Content:         content,
+				Components:      r.ResponseComponents.Components,
+				AllowedMentions: r.mentions(contentMentions()),
+			}
+			if r.Ephemeral {
+				data.Flags = discordgo.MessageFlagsEphemeral
+			}
+			err = Session.InteractionRespond(r.Ctx.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: data,
+			})
+		}
+		if err != nil {
+			SendErrorReport(r.guildId(), r.Ctx.Interaction.ChannelID, r.authorId(), "Unable to send interaction messages", err)
+		}
+		return
+	}
+
+	// Try sending the response in the configured output channel
+	// If that fails, send it in the channel the command was invoked from, as a reply if requested
+	messageSend := &discordgo.MessageSend{
+		Content:         content,
+		Components:      r.ResponseComponents.Components,
+		AllowedMentions: r.mentions(contentMentions()),
+	}
+	_, err := Session.ChannelMessageSendComplex(r.responseChannelId(), messageSend)
+	if err == nil {
+		return
+	}
+	if r.Reply {
+		messageSend.Reference = &discordgo.MessageReference{
+			MessageID: r.Ctx.Message.ID,
+			ChannelID: r.Ctx.Message.ChannelID,
+			GuildID:   r.guildId(),
+		}
+		_, err = ReplyToUser(r.Ctx.Message.ChannelID, messageSend)
+	} else {
+		_, err = Session.ChannelMessageSendComplex(r.Ctx.Message.ChannelID, messageSend)
+	}
+	if err != nil {
+		SendErrorReport(r.guildId(), r.Ctx.Message.ChannelID, r.authorId(), "Ultimately failed to send bot response", err)
+	}
+}
+
+// publicDeferral
+// Determine whether this is an ephemeral response to an interaction that was deferred publicly
+// Editing the deferred response would show the ephemeral response to everyone, see SetDeferEphemeral
+func (r *Response) publicDeferral() bool {
+	return r.Ephemeral && r.Ctx.deferred && !r.Ctx.deferredEphemeral
+}
+
+// sendEphemeralFollowup
+// Send an ephemeral response as a followup message, and delete the public deferred response it replaces
+func (r *Response) sendEphemeralFollowup(params *discordgo.WebhookParams) error {
+	params.Flags = discordgo.MessageFlagsEphemeral
+	if _, err := Session.FollowupMessageCreate(r.Ctx.Interaction, true, params); err != nil {
+		return err
+	}
+	r.Loading = false
+
+	// The placeholder only says the bot is thinking, so there is nothing useful to do if it can't be deleted
+	if err := Session.InteractionResponseDelete(r.Ctx.Interaction); err != nil {
+		log.Debugf("Failed to delete deferred response to interaction %s: %s", r.Ctx.Interaction.ID, err)
+	}
+	return nil
+}
+
+// SetColor
+// Set the color of the primary embed, and of the continuation embeds holding its extra fields
+func (r *Response) SetColor(color int) {
 	r.Embed.Color = color
+	for _, continuation := range r.continuations {
+		continuation.Color = color
+	}
+}
 
-	// If guild is nil, this is intended to be sent to Bot Admins
-	if r.Ctx.Guild == nil {
+// deliver
+// Send the embeds and components of the response to wherever the command was invoked from
+// This answers the interaction for slash commands, and otherwise uses the response channel or the invoking channel
+func (r *Response) deliver() {
+	// Sending will most likely fail, but try anyway so the failure is reported the usual way
+	if err := r.Validate(); err != nil {
+		log.Warningf("Response \"%s\" exceeds Discord's limits: %s", r.Embed.Title, err)
+	}
+
+	// If guild is nil and this isn't an interaction, this is intended to be sent to Bot Admins
+	// Interactions from DMs and user-installed apps can also have a nil guild, but those are answered below
+	if r.Ctx.Guild == nil && r.Ctx.Interaction == nil {
 		for admin := range botAdmins {
 			dmChannel, dmCreateErr := Session.UserChannelCreate(admin)
 			if dmCreateErr != nil {
 				// Since error reports also use DMs, sending this as an error report would be redundant
 				// Just log the error
 				log.Errorf("Failed sending Response DM to admin: %s; Response title: %s", admin, r.Embed.Title)
-				return
+				continue
 			}
 			_, dmSendErr := Session.ChannelMessageSendComplex(dmChannel.ID, &discordgo.MessageSend{
-				Embed:      r.Embed,
-				Components: r.ResponseComponents.Components,
+				Embeds:          r.allEmbeds(),
+				Components:      r.ResponseComponents.Components,
+				AllowedMentions: r.mentions(noMentions()),
 			})
 			if dmSendErr != nil {
 				// Since error reports also use DMs, sending this as an error report would be redundant
 				// Just log the error
 				log.Errorf("Failed sending Response DM to admin: %s; Response title: %s", admin, r.Embed.Title)
-				return
+				continue
 			}
-			return
 		}
+		return
 	}
 
 	// If this is a interaction (slash command)
@@ -224,43 +724,53 @@ func (r *Response) Send(success bool, title string, description string) {
 		// Some commands take a while to load
 		// Slash commands expect a response in 3 seconds or the interaction gets invalidated
 		if r.Loading {
+			// An ephemeral response can't be sent by editing a public deferred response
+			if r.publicDeferral() {
+				err := r.sendEphemeralFollowup(&discordgo.WebhookParams{
+					Embeds:          r.allEmbeds(),
+					Components:      r.ResponseComponents.Components,
+					AllowedMentions: r.mentions(noMentions()),
+				})
+				if err != nil {
+					SendErrorReport(r.guildId(), r.Ctx.Interaction.ChannelID, r.authorId(), "Unable to send interaction messages", err)
+				}
+				return
+			}
 			// Check to see if the command is ephemeral (only shown to the user)
 			if r.Ephemeral {
 				_, err := Session.InteractionResponseEdit(r.Ctx.Interaction, &discordgo.WebhookEdit{
-					Components: &r.ResponseComponents.Components,
-					Embeds: &[]*discordgo.MessageEmbed{
-						r.Embed,
-					},
+					Components:      &r.ResponseComponents.Components,
+					Embeds:          ToPtr(r.allEmbeds()),
+					AllowedMentions: r.mentions(noMentions()),
 				})
 				// Just in case the interaction gets removed.
 				if err != nil {
 					if err != nil {
-						SendErrorReport(r.Ctx.Guild.ID, r.Ctx.Interaction.ChannelID, r.Ctx.Message.Author.ID, "Unable to send interaction messages", err)
+						SendErrorReport(r.guildId(), r.Ctx.Interaction.ChannelID, r.authorId(), "Unable to send interaction messages", err)
 					}
-					if r.Ctx.Guild.Info.ResponseChannelId != "" {
-						_, err = Session.ChannelMessageSendEmbed(r.Ctx.Guild.Info.ResponseChannelId, r.Embed)
+					if r.responseChannelId() != "" {
+						_, err = Session.ChannelMessageSendEmbeds(r.responseChannelId(), r.allEmbeds())
 
 					} else {
-						_, err = Session.ChannelMessageSendEmbed(r.Ctx.Message.ChannelID, r.Embed)
+						_, err = Session.ChannelMessageSendEmbeds(r.Ctx.Message.ChannelID, r.allEmbeds())
 					}
 
 					if err != nil {
-						SendErrorReport(r.Ctx.Guild.ID, r.Ctx.Interaction.ChannelID, r.Ctx.Message.Author.ID, "Unable to send message", err)
+						SendErrorReport(r.guildId(), r.Ctx.Interaction.ChannelID, r.authorId(), "Unable to send message", err)
 					}
 				}
 			} else {
 				_, err := Session.InteractionResponseEdit(r.Ctx.Interaction, &discordgo.WebhookEdit{
-					Content: ToPtr[string](""),
-					Embeds: &[]*discordgo.MessageEmbed{
-						r.Embed,
-					},
-					Components: &r.ResponseComponents.Components,
+					Content:         ToPtr[string](""),
+					Embeds:          ToPtr(r.allEmbeds()),
+					Components:      &r.ResponseComponents.Components,
+					AllowedMentions: r.mentions(noMentions()),
 				})
 				// Just in case the interaction gets removed.
 				if err != nil {
-					_, err := Session.ChannelMessageSendEmbed(r.Ctx.Guild.Info.ResponseChannelId, r.Embed)
+					_, err := Session.ChannelMessageSendEmbeds(r.responseChannelId(), r.allEmbeds())
 					if err != nil {
-						_, err = Session.ChannelMessageSendEmbed(r.Ctx.Message.ChannelID, r.Embed)
+						_, err = Session.ChannelMessageSendEmbeds(r.Ctx.Message.ChannelID, r.allEmbeds())
 						if err != nil {
 						}
 					}
@@ -275,11 +785,10 @@ func (r *Response) Send(success bool, title string, description string) {
 				// Ephemeral is type 64 don't ask why
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Flags: 1 << 6,
-					Embeds: []*discordgo.MessageEmbed{
-						r.Embed,
-					},
-					Components: r.ResponseComponents.Components,
+					Flags:           1 << 6,
+					Embeds:          r.allEmbeds(),
+					Components:      r.ResponseComponents.Components,
+					AllowedMentions: r.mentions(noMentions()),
 				},
 			})
 			return
@@ -289,25 +798,24 @@ func (r *Response) Send(success bool, title string, description string) {
 		err := Session.InteractionRespond(r.Ctx.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Embeds: []*discordgo.MessageEmbed{
-					r.Embed,
-				},
-				Components: r.ResponseComponents.Components,
+				Embeds:          r.allEmbeds(),
+				Components:      r.ResponseComponents.Components,
+				AllowedMentions: r.mentions(noMentions()),
 			},
 		})
 		if err != nil {
 			if err != nil {
-				SendErrorReport(r.Ctx.Guild.ID, r.Ctx.Interaction.ChannelID, r.Ctx.Message.Author.ID, "Unable to send interaction messages", err)
+				SendErrorReport(r.guildId(), r.Ctx.Interaction.ChannelID, r.authorId(), "Unable to send interaction messages", err)
 			}
-			if r.Ctx.Guild.Info.ResponseChannelId != "" {
-				_, err = Session.ChannelMessageSendEmbed(r.Ctx.Guild.Info.ResponseChannelId, r.Embed)
+			if r.responseChannelId() != "" {
+				_, err = Session.ChannelMessageSendEmbeds(r.responseChannelId(), r.allEmbeds())
 
 			} else {
-				_, err = Session.ChannelMessageSendEmbed(r.Ctx.Message.ChannelID, r.Embed)
+				_, err = Session.ChannelMessageSendEmbeds(r.Ctx.Message.ChannelID, r.allEmbeds())
 			}
 
 			if err != nil {
-				SendErrorReport(r.Ctx.Guild.ID, r.Ctx.Interaction.ChannelID, r.Ctx.Message.Author.ID, "Unable to send message", err)
+				SendErrorReport(r.guildId(), r.Ctx.Interaction.ChannelID, r.authorId(), "Unable to send message", err)
 			}
 		}
 		return
@@ -315,36 +823,42 @@ func (r *Response) Send(success bool, title string, description string) {
 	// Try sending the response in the configured output channel
 	// If that fails, try sending the response in the current channel
 	// If THAT fails, send an error report
-	_, err := Session.ChannelMessageSendComplex(r.Ctx.Guild.Info.ResponseChannelId, &discordgo.MessageSend{
-		Embed:      r.Embed,
-		Components: r.ResponseComponents.Components,
+	_, err := Session.ChannelMessageSendComplex(r.responseChannelId(), &discordgo.MessageSend{
+		Embeds:          r.allEmbeds(),
+		Components:      r.ResponseComponents.Components,
+		AllowedMentions: r.mentions(noMentions()),
 	})
 	if err != nil && r.Reply {
 		// Reply to user if no output channel
 		_, err = ReplyToUser(r.Ctx.Message.ChannelID, &discordgo.MessageSend{
-			Embed:      r.Embed,
+			Embeds:     r.allEmbeds(),
 			Components: r.ResponseComponents.Components,
 			Reference: &discordgo.MessageReference{
 				MessageID: r.Ctx.Message.ID,
 				ChannelID: r.Ctx.Message.ChannelID,
-				GuildID:   r.Ctx.Guild.ID,
-			},
-			AllowedMentions: &discordgo.MessageAllowedMentions{
-				Parse: []discordgo.AllowedMentionType{},
+				GuildID:   r.guildId(),
 			},
+			AllowedMentions: r.mentions(noMentions()),
 		})
 		if err != nil {
-			SendErrorReport(r.Ctx.Guild.ID, r.Ctx.Message.ChannelID, r.Ctx.Message.Author.ID, "Ultimately failed to send bot response", err)
+			SendErrorReport(r.guildId(), r.Ctx.Message.ChannelID, r.authorId(), "Ultimately failed to send bot response", err)
 		}
 	} else if !r.Reply {
 		// If the command does not want to reply lets just send it to the channel the command was invoked
 		_, err = Session.ChannelMessageSendComplex(r.Ctx.Message.ChannelID, &discordgo.MessageSend{
-			Embed:      r.Embed,
-			Components: r.ResponseComponents.Components,
+			Embeds:          r.allEmbeds(),
+			Components:      r.ResponseComponents.Components,
+			AllowedMentions: r.mentions(noMentions()),
 		})
 	}
 }
 
+// ErrorResponseTTL
+// How long error responses stay visible before they are deleted
+var ErrorResponseTTL = 10 * time.Second
+
+// ErrorResponse
+// Respond to an interaction with an error embed, then delete it once ErrorResponseTTL has passed
 func ErrorResponse(i *discordgo.Interaction, errorMsg string, trigger string) {
 	var errorEmbed = CreateEmbed(0xff3232, "Error", errorMsg, []*discordgo.MessageEmbedField{
 		{
@@ -353,7 +867,7 @@ func ErrorResponse(i *discordgo.Interaction, errorMsg string, trigger string) {
 		},
 		{
 			Name:  "Invoked by:",
-			Value: i.Member.User.Mention(),
+			Value: interactionUser(i).Mention(),
 		},
 	})
 	Session.InteractionRespond(i, &discordgo.InteractionResponse{
@@ -365,10 +879,52 @@ func ErrorResponse(i *discordgo.Interaction, errorMsg string, trigger string) {
 		},
 	})
 
-	time.AfterFunc(time.Second*5, func() {
-		time.Sleep(time.Second * 4)
-		Session.InteractionResponseDelete(i)
+	time.AfterFunc(ErrorResponseTTL, func() {
+		// The interaction may already be gone, so there is nothing useful to do with an error here
+		if err := Session.InteractionResponseDelete(i); err != nil {
+			log.Debugf("Failed to delete error response to interaction %s: %s", i.ID, err)
+		}
+	})
+}
+
+// ErrorResponseMessage
+// Send an error embed to a channel, then delete it once ErrorResponseTTL has passed
+// This is the prefix command equivalent of ErrorResponse
+func ErrorResponseMessage(channelId string, errorMsg string, trigger string) {
+	var errorEmbed = CreateEmbed(0xff3232, "Error", errorMsg, []*discordgo.MessageEmbedField{
+		{
+			Name:  "Command Used",
+			Value: trigger,
+		},
+	})
+	message, err := Session.ChannelMessageSendEmbed(channelId, errorEmbed)
+	if err != nil {
+		log.Errorf("Failed to send error response to channel %s: %s", channelId, err)
+		return
+	}
+
+	time.AfterFunc(ErrorResponseTTL, func() {
+		// If the bot can't delete messages here, just leave the message
+		_ = Session.ChannelMessageDelete(channelId, message.ID)
+	})
+}
+
+// ephemeralErrorResponse
+// Respond to an interaction with an error embed that is only shown to the user who invoked it
+func ephemeralErrorResponse(i *discordgo.Interaction, errorMsg string) {
+	err := Session.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			// Ephemeral is type 64 don't ask why
+			Flags: 1 << 6,
+			Embeds: []*discordgo.MessageEmbed{
+				CreateEmbed(ColorFailure, "Error", errorMsg, nil),
+			},
+		},
 	})
+	if err != nil {
+		log.Errorf("Failed to send error response to interaction %s: %s", i.ID, err)
+	}
 }
 
 func (r *Response) AcknowledgeInteraction() {
@@ -0,0 +1,109 @@
+package framework
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// interactionContext
+// Create the context a slash command gets when it is used outside a guild, e.g. through a user-installed app
+func interactionContext(deferred bool) *Context {
+	user := &discordgo.User{ID: "600000000000000001", Username: "dm-user"}
+	return &Context{
+		Cmd: CommandInfo{Trigger: "test", Arguments: CreateCommandInfo("test", "", true, "").Arguments},
+		Interaction: &discordgo.Interaction{
+			ID:        "600000000000000002",
+			AppID:     "600000000000000003",
+			Token:     "token",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "600000000000000004",
+			User:      user,
+		},
+		Message: &discordgo.Message{
+			Author:    user,
+			ChannelID: "600000000000000004",
+		},
+		deferred: deferred,
+	}
+}
+
+// countRequests
+// Count the requests that start with the given method and contain the given path fragment
+func countRequests(requests []string, method string, fragment string) int {
+	count := 0
+	for _, request := range requests {
+		if strings.HasPrefix(request, method+" ") && strings.Contains(request, fragment) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSendWithoutGuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		success  bool
+		deferred bool
+		status   int
+		method   string
+		fragment string
+	}{
+		{"success", true, false, http.StatusOK, http.MethodPost, "/callback"},
+		{"failure", false, false, http.StatusOK, http.MethodPost, "/callback"},
+		{"deferred", true, true, http.StatusOK, http.MethodPatch, "/messages/@original"},
+		// When the interaction can't be answered, the response falls back to a plain message in the channel
+		{"interaction failed", true, false, http.StatusNotFound, http.MethodPost, "/channels/600000000000000004/messages"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeSession(t, func(req *http.Request) (int, string) {
+				if strings.Contains(req.URL.Path, "/interactions/") || strings.Contains(req.URL.Path, "/webhooks/") {
+					return tt.status, `{"code":10062,"message":"Unknown interaction"}`
+				}
+				return http.StatusOK, "{}"
+			})
+
+			NewResponse(interactionContext(tt.deferred), false, false).Send(tt.success, "title", "description")
+
+			if countRequests(fake.Requests(), tt.method, tt.fragment) == 0 {
+				t.Errorf("no %s request to %s was made; requests: %v", tt.method, tt.fragment, fake.Requests())
+			}
+		})
+	}
+}
+
+func TestAppendUsageWithoutCommand(t *testing.T) {
+	r := NewResponse(&Context{}, false, false)
+	r.AppendUsage()
+	if len(r.Embed.Fields) != 0 {
+		t.Errorf("AppendUsage added %d fields for a context without a command", len(r.Embed.Fields))
+	}
+
+	// A command without arguments has a usage of just its trigger
+	r = NewResponse(&Context{Cmd: CommandInfo{Trigger: "ping"}}, false, false)
+	r.AppendUsage()
+	if len(r.Embed.Fields) != 2 || r.Embed.Fields[1].Value != "```\nping\n```" {
+		t.Errorf("AppendUsage for a command without arguments added %v", r.Embed.Fields)
+	}
+}
+
+func TestEphemeralResponseToPublicDeferral(t *testing.T) {
+	fake := useFakeSession(t, nil)
+
+	NewResponse(interactionContext(true), false, true).Send(true, "title", "description")
+
+	requests := fake.Requests()
+	if countRequests(requests, http.MethodPost, "/webhooks/600000000000000003/token") != 1 {
+		t.Errorf("the response was not sent as a followup; requests: %v", requests)
+	}
+	if countRequests(requests, http.MethodDelete, "/messages/@original") != 1 {
+		t.Errorf("the public deferred response was not deleted; requests: %v", requests)
+	}
+	if countRequests(requests, http.MethodPatch, "/messages/@original") != 0 {
+		t.Errorf("the public deferred response was edited; requests: %v", requests)
+	}
+}
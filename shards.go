@@ -0,0 +1,147 @@
+package framework
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// shards.go
+// This file contains sharding, which splits the bot's guilds across several gateway connections
+// Discord requires bots in more than 2500 guilds to be sharded
+//
+// Every shard has its own discordgo session, and Session is always shard 0
+// REST calls (sending messages, banning members, etc.) work from any shard, so Session can keep being used for them
+// Gateway and state calls (presence, Session.State) only cover the guilds of their own shard,
+// so use SessionForGuild to get the right session for a guild. A guild is on shard (guildID >> 22) % shardCount
+
+// shardCount
+// How many shards to start. 0 or less lets Discord decide
+var shardCount = 1
+
+// shardIdentifyDelay
+// How long to wait between opening shards, since Discord only allows one identify every 5 seconds
+const shardIdentifyDelay = 5 * time.Second
+
+// Sessions
+// The session of every shard, in shard order. Sessions[0] is the same session as Session
+var Sessions []*discordgo.Session
+
+// SetShardCount
+// Set how many shards to start. This must be called before Start
+// A count of 0 or less uses the number of shards Discord recommends for the bot
+func SetShardCount(n int) {
+	shardCount = n
+}
+
+// ShardForGuild
+// Get the shard a guild is on
+func ShardForGuild(guildId string) int {
+	if len(Sessions) <= 1 {
+		return 0
+	}
+	id, err := strconv.ParseUint(guildId, 10, 64)
+	if err != nil {
+		// DMs and invalid IDs are always sent to shard 0
+		return 0
+	}
+	return int((id >> 22) % uint64(len(Sessions)))
+}
+
+// SessionForGuild
+// Get the session of the shard a guild is on
+func SessionForGuild(guildId string) *discordgo.Session {
+	if len(Sessions) == 0 {
+		return Session
+	}
+	return Sessions[ShardForGuild(guildId)]
+}
+
+// createShards
+// Create the session of every shard, using Session as shard 0
+func createShards() error {
+	count := shardCount
+	if count <= 0 {
+		gateway, err := Session.GatewayBot()
+		if err != nil {
+			return err
+		}
+		count = gateway.Shards
+	}
+	if count <= 0 {
+		return errors.New("discord did not recommend a shard count")
+	}
+
+	Sessions = make([]*discordgo.Session, count)
+	for i := range Sessions {
+		shard := Session
+		if i > 0 {
+			var err error
+			if shard, err = discordgo.New("Bot " + botToken); err != nil {
+				return err
+			}
+		}
+
+		// Setup State specific variables
+		shard.State.MaxMessageCount = MessageState
+		shard.LogLevel = discordgo.LogWarning
+		shard.SyncEvents = false
+		shard.Identify.Intents = botIntents
+		shard.ShardID = i
+		shard.ShardCount = count
+
+		// Set the bots status once the session is ready; Discord ignores presence updates sent before that
+		shard.AddHandler(presenceReadyHandler)
+
+		Sessions[i] = shard
+	}
+	return nil
+}
+
+// openShards
+// Connect every shard to Discord, one after another
+func openShards() error {
+	for i, shard := range Sessions {
+		if i > 0 {
+			time.Sleep(shardIdentifyDelay)
+		}
+		if len(Sessions) > 1 {
+			log.Infof("Connecting shard %d of %d...", i+1, len(Sessions))
+		}
+		if err := shard.Open(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeShards
+// Disconnect every shard from Discord
+func closeShards() error {
+	var firstErr error
+	for _, shard := range Sessions {
+		if err := shard.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// shardsReady
+// Check if every shard is connected and ready
+func shardsReady() bool {
+	if len(Sessions) == 0 {
+		return false
+	}
+	for _, shard := range Sessions {
+		shard.RLock()
+		ready := shard.DataReady
+		shard.RUnlock()
+		if !ready {
+			return false
+		}
+	}
+	return true
+}
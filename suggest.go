@@ -0,0 +1,118 @@
+package framework
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// suggest.go
+// This file contains "did you mean" suggestions for prefix commands that don't exist
+
+// maxSuggestionDistance
+// The maximum amount of edits between an unknown trigger and a command for it to be suggested
+const maxSuggestionDistance = 2
+
+// suggestionCooldown
+// How long a channel has to wait before it gets another suggestion, so typos don't turn into spam
+const suggestionCooldown = 30 * time.Second
+
+// lastSuggestions
+// A map of channel IDs to the last time a suggestion was sent there
+var lastSuggestions = make(map[string]time.Time)
+
+// lastSuggestionsLock
+// Guards lastSuggestions
+var lastSuggestionsLock sync.Mutex
+
+// SetSuggestCommands
+// Set whether the bot suggests the closest command when a prefix command doesn't exist, then save the guild data
+func (g *Guild) SetSuggestCommands(suggest bool) error {
+	return g.update(func() {
+		g.Info.SuggestCommands = suggest
+	})
+}
+
+// levenshtein
+// Get the amount of single character insertions, deletions and substitutions needed to turn a into b
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// minInt
+// Get the smaller of two ints
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// closestCommand
+// Find the trigger or alias closest to an unknown trigger, if one is close enough
+// Hidden commands are never suggested
+func closestCommand(trigger string) (string, bool) {
+	best := ""
+	bestDistance := maxSuggestionDistance + 1
+
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+	for alias, commandTrigger := range commandAliases {
+		if command, ok := commands[commandTrigger]; !ok || command.Info.Hidden {
+			continue
+		}
+
+		distance := levenshtein(trigger, alias)
+		// Very short triggers are close to everything, so the distance has to be less than the trigger length
+		if distance < bestDistance && distance < len([]rune(trigger)) {
+			best = alias
+			bestDistance = distance
+		}
+	}
+	return best, best != ""
+}
+
+// suggestCommand
+// Reply to an unknown prefix command with the closest command, if the guild wants suggestions
+// Each channel gets at most one suggestion per suggestionCooldown
+func suggestCommand(g *Guild, channelId string, trigger string) {
+	if !g.Info.SuggestCommands {
+		return
+	}
+
+	suggestion, ok := closestCommand(strings.ToLower(trigger))
+	if !ok {
+		return
+	}
+
+	lastSuggestionsLock.Lock()
+	if time.Since(lastSuggestions[channelId]) < suggestionCooldown {
+		lastSuggestionsLock.Unlock()
+		return
+	}
+	lastSuggestions[channelId] = time.Now()
+	lastSuggestionsLock.Unlock()
+
+	_, err := Session.ChannelMessageSendEmbed(channelId, CreateEmbed(g.GetFailureColor(), "Unknown command", "Did you mean `"+g.Info.Prefix+suggestion+"`?", nil))
+	if err != nil {
+		log.Errorf("Failed to suggest a command in channel %s: %s", channelId, err)
+	}
+}
@@ -0,0 +1,213 @@
+package framework
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// tempbans.go
+// This file contains temporary bans, which are persisted in a guild's arbitrary storage so they survive restarts
+
+// TempBanRecord
+// A single temporary ban, and the unix timestamp of when it should be lifted
+type TempBanRecord struct {
+	UserId string `json:"user_id"`
+	Until  int64  `json:"until"`
+}
+
+// tempBansKey
+// The storage key that holds a guild's temporary bans
+const tempBansKey = "temp_bans"
+
+// GetTempBans
+// Retrieve all the temporary bans that have not been lifted yet in this guild
+func (g *Guild) GetTempBans() ([]TempBanRecord, error) {
+	var records []TempBanRecord
+	_, err := g.getStorageAs(tempBansKey, &records)
+	if err != nil {
+		return nil, errors.New("failed to cast the data to type \"[]TempBanRecord\"")
+	}
+
+	return records, nil
+}
+
+// storeTempBansLocked
+// Store the list of temporary bans while g.lock is held, then save the guild data
+// Callers must read, change and store the list under the same lock, so concurrent changes aren't lost
+func (g *Guild) storeTempBansLocked(records []TempBanRecord) error {
+	if len(records) == 0 {
+		delete(g.Info.Storage, tempBansKey)
+	} else {
+		g.Info.Storage[tempBansKey] = records
+	}
+	return g.commitLocked()
+}
+
+// TempBan
+// Ban a user immediately, and schedule them to be unbanned at the given time
+// The ban is stored in this guild's storage, so it will still be lifted if the bot restarts in the meantime
+func (g *Guild) TempBan(userId string, reason string, until time.Time) error {
+	if !until.After(time.Now()) {
+		return errors.New("temporary ban must end in the future")
+	}
+
+	// Make sure the USER exists, because they may not be a member
+	user, err := GetUser(userId)
+	if err != nil {
+		return err
+	}
+
+	err = g.Ban(user.ID, reason, 0)
+	if err != nil {
+		return err
+	}
+
+	err = g.addTempBan(TempBanRecord{
+		UserId: user.ID,
+		Until:  until.Unix(),
+	})
+
+	// The unban is scheduled even if saving failed, so the ban is still lifted as long as the bot keeps running
+	g.scheduleUnban(user.ID, until)
+	return err
+}
+
+// addTempBan
+// Store a temporary ban, replacing any existing one for the same user so only the newest end time is kept
+func (g *Guild) addTempBan(ban TempBanRecord) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	var records []TempBanRecord
+	if _, err := g.getStorageAsLocked(tempBansKey, &records); err != nil {
+		return errors.New("failed to cast the data to type \"[]TempBanRecord\"")
+	}
+
+	var newRecords []TempBanRecord
+	for _, record := range records {
+		if record.UserId != ban.UserId {
+			newRecords = append(newRecords, record)
+		}
+	}
+	newRecords = append(newRecords, ban)
+	return g.storeTempBansLocked(newRecords)
+}
+
+// scheduleUnban
+// Lift a temporary ban once its end time has been reached
+func (g *Guild) scheduleUnban(userId string, until time.Time) {
+	time.AfterFunc(time.Until(until), func() {
+		g.liftTempBan(userId)
+	})
+}
+
+// liftTempBan
+// Unban a temporarily banned user, and remove their record from storage
+// If the ban was replaced by a newer one that hasn't ended yet, nothing happens
+func (g *Guild) liftTempBan(userId string) {
+	if !g.tempBanEnded(userId) {
+		return
+	}
+
+	// The ban may have been lifted manually (possibly while the bot was offline), so make sure it still exists
+	_, err := Session.GuildBan(g.ID, userId)
+	var restErr *discordgo.RESTError
+	switch {
+	case errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownBan:
+		log.Infof("Temporary ban for %s in guild %s was already lifted", userId, g.ID)
+	case err != nil:
+		// Keep the record, so the unban is attempted again on the next startup
+		SendErrorReport(g.ID, "", userId, "Failed to check temporary ban", err)
+		return
+	default:
+		if err = Session.GuildBanDelete(g.ID, userId); err != nil {
+			SendErrorReport(g.ID, "", userId, "Failed to lift temporary ban", err)
+			return
+		}
+	}
+
+	if err = g.removeTempBan(userId); err != nil {
+		SendErrorReport(g.ID, "", userId, "Failed to remove lifted temporary ban", err)
+	}
+}
+
+// tempBanEnded
+// Determine whether the user has a stored temporary ban that has reached its end time
+func (g *Guild) tempBanEnded(userId string) bool {
+	records, err := g.GetTempBans()
+	if err != nil {
+		SendErrorReport(g.ID, "", userId, "Failed to read temporary bans", err)
+		return false
+	}
+
+	for _, record := range records {
+		if record.UserId == userId {
+			// If the user was banned again with a later end time, the newer timer will handle it
+			return !time.Unix(record.Until, 0).After(time.Now())
+		}
+	}
+	return false
+}
+
+// removeTempBan
+// Remove the record of a lifted temporary ban, unless the user was banned again in the meantime
+// The list is read and written under g.lock, so removing records can't bring back other lifted bans
+func (g *Guild) removeTempBan(userId string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	var records []TempBanRecord
+	if _, err := g.getStorageAsLocked(tempBansKey, &records); err != nil {
+		return errors.New("failed to cast the data to type \"[]TempBanRecord\"")
+	}
+
+	var newRecords []TempBanRecord
+	for _, record := range records {
+		if record.UserId != userId || time.Unix(record.Until, 0).After(time.Now()) {
+			newRecords = append(newRecords, record)
+		}
+	}
+	if len(newRecords) == len(records) {
+		return nil
+	}
+	return g.storeTempBansLocked(newRecords)
+}
+
+// restoreTempBans
+// Go through the stored temporary bans of every guild, lifting the ones that have ended and scheduling the rest
+// The ended bans of a guild are lifted one after another, in the background
+func restoreTempBans() {
+	restored := 0
+	for _, g := range AllGuilds() {
+		records, err := g.GetTempBans()
+		if err != nil {
+			log.Errorf("Failed to read temporary bans for guild %s: %s", g.ID, err)
+			continue
+		}
+
+		var ended []string
+		for _, record := range records {
+			until := time.Unix(record.Until, 0)
+			if until.After(time.Now()) {
+				g.scheduleUnban(record.UserId, until)
+				restored++
+			} else {
+				ended = append(ended, record.UserId)
+			}
+		}
+
+		if len(ended) > 0 {
+			go func(g *Guild, ended []string) {
+				for _, userId := range ended {
+					g.liftTempBan(userId)
+				}
+			}(g, ended)
+		}
+	}
+
+	if restored > 0 {
+		log.Infof("Restored %d temporary ban(s)", restored)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"regexp"
 	"runtime"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/dlclark/regexp2"
@@ -15,40 +18,48 @@ import (
 // util.go
 // This file contains utility functions, simplifying redundant tasks
 
-// RemoveItem
-// Remove an item from a slice by value
-func RemoveItem(slice []string, delete string) []string {
-	var newSlice []string
+// Remove
+// Remove every occurrence of a value from a slice
+func Remove[T comparable](slice []T, value T) []T {
+	var newSlice []T
 	for _, elem := range slice {
-		if elem != delete {
+		if elem != value {
 			newSlice = append(newSlice, elem)
 		}
 	}
 	return newSlice
 }
 
-// RemoveItems
+// RemoveIndexes
 // Removes items from a slice by index
-func RemoveItems(slice []string, indexes []int) []string {
-	newSlice := make([]string, len(slice))
-	if len(indexes) >= len(slice) {
-		return newSlice
-	}
-	copy(newSlice, slice)
+// Indexes that are out of range are ignored, and the original slice is left untouched
+func RemoveIndexes[T any](slice []T, indexes ...int) []T {
+	remove := make(map[int]bool, len(indexes))
 	for _, v := range indexes {
-		if len(newSlice) < v+1 && v != 0 {
-			v = v - 1
+		remove[v] = true
+	}
+
+	newSlice := make([]T, 0, len(slice))
+	for i, elem := range slice {
+		if !remove[i] {
+			newSlice = append(newSlice, elem)
 		}
-		//newSlice[v] = newSlice[len(newSlice)-1]
-		//newSlice[len(newSlice)-1] = ""
-		//newSlice = newSlice[:len(newSlice)-1]
-		copy(newSlice[v:], newSlice[v+1:])    // Shift a[i+1:] left one index.
-		newSlice[len(newSlice)-1] = ""        // Erase last element (write zero value).
-		newSlice = newSlice[:len(newSlice)-1] // Truncate slice.
 	}
 	return newSlice
 }
 
+// RemoveItem
+// Remove an item from a slice by value
+func RemoveItem(slice []string, delete string) []string {
+	return Remove(slice, delete)
+}
+
+// RemoveItems
+// Removes items from a slice by index
+func RemoveItems(slice []string, indexes []int) []string {
+	return RemoveIndexes(slice, indexes...)
+}
+
 // EnsureNumbers
 // Given a string, ensure it contains only numbers
 // This is useful for stripping letters and formatting characters from user/role pings
@@ -155,10 +166,108 @@ func GetUser(userId string) (*discordgo.User, error) {
 	return Session.User(cleanedId)
 }
 
+// Truncate
+// Shorten a string to at most maxLength characters, ending it with an ellipsis if it was cut off
+func Truncate(in string, maxLength int) string {
+	runes := []rune(in)
+	if len(runes) <= maxLength {
+		return in
+	}
+	if maxLength <= 0 {
+		return ""
+	}
+	if maxLength == 1 {
+		return string(runes[:maxLength])
+	}
+	return string(runes[:maxLength-1]) + "…"
+}
+
+// SanitizeMentions
+// Break up @everyone and @here with a zero-width space, so echoed user input can't ping a whole guild
+// Use this on user input that is put into plain text messages; AllowMentions controls the rest
+func SanitizeMentions(content string) string {
+	content = strings.ReplaceAll(content, "@everyone", "@\u200beveryone")
+	return strings.ReplaceAll(content, "@here", "@\u200bhere")
+}
+
+// markdownEscaper
+// Escapes the characters Discord uses for markdown formatting
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"*", "\\*",
+	"_", "\\_",
+	"~", "\\~",
+	"`", "\\`",
+	"|", "\\|",
+	">", "\\>",
+)
+
+// EscapeMarkdown
+// Escape markdown formatting in user content (e.g. usernames or reasons), so it is shown as typed in messages and embeds
+func EscapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// EscapeCodeBlock
+// Make user content safe to put inside a code block, where backslashes don't escape anything
+// A zero-width space is put after every backtick, so the content can't close the code block early
+func EscapeCodeBlock(s string) string {
+	return strings.ReplaceAll(s, "`", "`\u200b")
+}
+
+// recoveredError
+// Convert any value recovered from a panic into an error
+// The stack trace is left out, so the same panic always has the same message; see panicStack
+func recoveredError(r interface{}) error {
+	return fmt.Errorf("%v", r)
+}
+
+// panicStack
+// Get the stack trace of a panic, to add to its error report
+// This must be called from the deferred function that recovered, so the trace still contains the panic
+func panicStack() string {
+	stack := string(debug.Stack())
+
+	// Skip the frames of the recovery itself, so the trace starts where the panic happened
+	if i := strings.Index(stack, "\npanic("); i >= 0 {
+		frames := strings.SplitN(stack[i+1:], "\n", 3)
+		if len(frames) == 3 {
+			return frames[2]
+		}
+	}
+	return stack
+}
+
+// maxRateLimitRetries
+// How many times withRetry will retry a request that was rate limited
+const maxRateLimitRetries = 3
+
+// withRetry
+// Run a Discord API call, retrying it if it was rate limited
+// Between attempts, it waits for as long as Discord asked it to. Any other error is returned immediately
+func withRetry(fn func() error) error {
+	var err error
+	for attempt := 0; attempt <= maxRateLimitRetries; attempt++ {
+		err = fn()
+
+		var rateLimitErr *discordgo.RateLimitError
+		if !errors.As(err, &rateLimitErr) || rateLimitErr.RateLimit == nil || rateLimitErr.TooManyRequests == nil {
+			return err
+		}
+		if attempt == maxRateLimitRetries {
+			break
+		}
+
+		log.Warningf("Rate limited on %s, retrying in %s", rateLimitErr.URL, rateLimitErr.RetryAfter)
+		time.Sleep(rateLimitErr.RetryAfter)
+	}
+	return err
+}
+
 // logErrorReportFailure
 // If an error report fails to send, log the failure
 func logErrorReportFailure(recipient string, dmErr error, guildId string, channelId string, userId string, errTitle string, origErr error) {
-	log.Errorf("[REPORT] Failed to DM report to %s: %s", recipient, dmErr)
+	log.Errorf("[REPORT] Failed to send report to %s: %s", recipient, dmErr)
 	log.Error("[REPORT] ---------- BEGIN ERROR REPORT ----------")
 	log.Error("[REPORT]     Report title: " + errTitle)
 	// Can't .Error a nil error
@@ -171,11 +280,191 @@ func logErrorReportFailure(recipient string, dmErr error, guildId string, channe
 	log.Error("[REPORT] ----------- END ERROR REPORT -----------")
 }
 
+// errorReportWindow
+// Identical error reports sent within this window are collapsed into a single report
+// When the window closes, a summary with the amount of repeated occurrences is sent
+// A window of 0 disables deduplication
+var errorReportWindow = time.Minute
+
+// pendingReport
+// The first occurrence of an error report, and how many times it has occurred in the current window
+type pendingReport struct {
+	guildId     string
+	channelId   string
+	userId      string
+	title       string
+	err         error
+	detail      string
+	occurrences int
+}
+
+// pendingReports
+// A map of error report keys (title + error message) to the reports sent in the current window
+var pendingReports = make(map[string]*pendingReport)
+
+// pendingReportsLock
+// Guards pendingReports, since error reports can be sent from any goroutine
+var pendingReportsLock sync.Mutex
+
+// SetErrorReportWindow
+// Set the window in which identical error reports are collapsed into one. A window of 0 disables deduplication
+func SetErrorReportWindow(window time.Duration) {
+	if window < 0 {
+		window = 0
+	}
+	errorReportWindow = window
+}
+
 // SendErrorReport
 // Send an error report as a DM to all of the registered bot administrators
-func SendErrorReport(guildId string, channelId string, userId string, title string, err error) {
+// Optional details, like a stack trace, are added to the report, but don't count towards it being identical to another
+// Identical reports are rate limited; see errorReportWindow
+func SendErrorReport(guildId string, channelId string, userId string, title string, err error, details ...string) {
+	detail := strings.Join(details, "\n")
+
 	// Log a general error
 	log.Errorf("[REPORT] %s (%s)", title, err)
+	if detail != "" {
+		log.Errorf("[REPORT] %s", detail)
+	}
+
+	window := errorReportWindow
+	if window <= 0 {
+		deliverErrorReport(guildId, channelId, userId, title, err, detail, 1)
+		return
+	}
+
+	// Key the report on its title and error message, since details like stack traces differ between occurrences
+	key := title
+	if err != nil {
+		key += "\x00" + err.Error()
+	}
+
+	pendingReportsLock.Lock()
+	if report, ok := pendingReports[key]; ok {
+		// This report was already sent in the current window; just count it
+		report.occurrences++
+		pendingReportsLock.Unlock()
+		return
+	}
+	pendingReports[key] = &pendingReport{
+		guildId:     guildId,
+		channelId:   channelId,
+		userId:      userId,
+		title:       title,
+		err:         err,
+		detail:      detail,
+		occurrences: 1,
+	}
+	pendingReportsLock.Unlock()
+
+	// Send the summary when the window closes
+	time.AfterFunc(window, func() {
+		flushErrorReport(key)
+	})
+
+	deliverErrorReport(guildId, channelId, userId, title, err, detail, 1)
+}
+
+// flushErrorReport
+// Close the window of a pending error report, and send a summary if it occurred more than once
+func flushErrorReport(key string) {
+	pendingReportsLock.Lock()
+	report, ok := pendingReports[key]
+	delete(pendingReports, key)
+	pendingReportsLock.Unlock()
+
+	if !ok || report.occurrences <= 1 {
+		return
+	}
+
+	deliverErrorReport(report.guildId, report.channelId, report.userId, report.title, report.err, report.detail, report.occurrences)
+}
+
+// errorReportChannel
+// The channel that error reports are posted to. If blank, error reports are sent as DMs to the bot administrators
+var errorReportChannel = ""
+
+// SetErrorReportChannel
+// Post error reports to a channel instead of DMing every bot administrator
+// DMs are still used as a fallback if the channel can't be posted to. Set to a blank string to only use DMs
+func SetErrorReportChannel(channelId string) {
+	errorReportChannel = channelId
+}
+
+// createErrorReportEmbed
+// Create the embed used to send an error report, only adding the fields that aren't blank
+func createErrorReportEmbed(guildId string, channelId string, userId string, title string, err error, detail string, occurrences int) *discordgo.MessageEmbed {
+	// Create a generic embed
+	reportEmbed := CreateEmbed(ColorFailure, "ERROR REPORT", title, nil)
+
+	// Add fields if they aren't blank
+	if guildId != "" {
+		reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Guild ID:",
+			Value:  guildId,
+			Inline: false,
+		})
+	}
+
+	if channelId != "" {
+		reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Channel ID:",
+			Value:  channelId,
+			Inline: false,
+		})
+	}
+
+	if userId != "" {
+		reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
+			Name:   "User ID:",
+			Value:  userId,
+			Inline: false,
+		})
+	}
+
+	if err != nil {
+		reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Full error:",
+			Value:  Truncate(err.Error(), 1024),
+			Inline: false,
+		})
+	}
+
+	if detail != "" {
+		reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Details:",
+			Value:  "```\n" + Truncate(EscapeCodeBlock(detail), 1024-len("```\n\n```")) + "\n```",
+			Inline: false,
+		})
+	}
+
+	if occurrences > 1 {
+		reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Occurrences:",
+			Value:  fmt.Sprintf("%d times in the last %s", occurrences, errorReportWindow),
+			Inline: false,
+		})
+	}
+
+	return reportEmbed
+}
+
+// deliverErrorReport
+// Send an error report to the error report channel, or as a DM to all of the registered bot administrators
+// If the report occurred more than once, the amount of occurrences is included
+func deliverErrorReport(guildId string, channelId string, userId string, title string, err error, detail string, occurrences int) {
+	reportEmbed := createErrorReportEmbed(guildId, channelId, userId, title, err, detail, occurrences)
+
+	// Try the error report channel first
+	// A failure here is only logged; sending it as another error report could loop forever
+	if errorReportChannel != "" {
+		_, sendErr := Session.ChannelMessageSendEmbed(errorReportChannel, reportEmbed)
+		if sendErr == nil {
+			return
+		}
+		logErrorReportFailure("channel "+errorReportChannel, sendErr, guildId, channelId, userId, title, err)
+	}
 
 	// Iterate through all the admins
 	for admin := range botAdmins {
@@ -187,42 +476,6 @@ func SendErrorReport(guildId string, channelId string, userId string, title stri
 			continue
 		}
 
-		// Create a generic embed
-		reportEmbed := CreateEmbed(ColorFailure, "ERROR REPORT", title, nil)
-
-		// Add fields if they aren't blank
-		if guildId != "" {
-			reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
-				Name:   "Guild ID:",
-				Value:  guildId,
-				Inline: false,
-			})
-		}
-
-		if channelId != "" {
-			reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
-				Name:   "Channel ID:",
-				Value:  channelId,
-				Inline: false,
-			})
-		}
-
-		if userId != "" {
-			reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
-				Name:   "User ID:",
-				Value:  userId,
-				Inline: false,
-			})
-		}
-
-		if err != nil {
-			reportEmbed.Fields = append(reportEmbed.Fields, &discordgo.MessageEmbedField{
-				Name:   "Full error:",
-				Value:  err.Error(),
-				Inline: false,
-			})
-		}
-
 		_, dmSendErr := Session.ChannelMessageSendEmbed(dmChannel.ID, reportEmbed)
 		if dmSendErr != nil {
 			logErrorReportFailure(admin, dmSendErr, guildId, channelId, userId, title, err)
@@ -232,10 +485,12 @@ func SendErrorReport(guildId string, channelId string, userId string, title stri
 }
 
 // ParseTime
-// Parses time strings
-func ParseTime(content string) (int, string) {
+// Parse a time string like "1d12h" into a duration, and a human-readable string for displaying it (e.g. "1 Day & 12 Hours")
+// The units are s, m, h, d, w, mo and y; a month counts as 30 days, and a year as 365 days
+// Returns an error if the string contains no durations
+func ParseTime(content string) (time.Duration, string, error) {
 	if content == "" {
-		return 0, "error lol"
+		return 0, "", errors.New("no duration was given")
 	}
 	duration := 0
 
@@ -243,7 +498,7 @@ func ParseTime(content string) (int, string) {
 
 	matches := FindAllString(TimeRegexes["all"], content)
 	if len(matches) <= 0 {
-		return 0, "error lol"
+		return 0, "", fmt.Errorf("%q is not a valid duration", content)
 	}
 	for _, v := range matches {
 		// Grab only the letters out of the duration, to detect the unit
@@ -265,16 +520,32 @@ func ParseTime(content string) (int, string) {
 			duration = multiplier*60*60*24 + duration
 		case "w":
 			duration = multiplier*60*60*24*7 + duration
+		case "mo":
+			duration = multiplier*60*60*24*30 + duration
 		case "y":
-			duration = multiplier*60*60*24*7*52 + duration
+			duration = multiplier*60*60*24*365 + duration
 		default:
 			break
 		}
 	}
 
-	return duration, createDisplayDurationString(content)
+	return time.Duration(duration) * time.Second, createDisplayDurationString(content), nil
 }
 
+// durationUnitNames
+// The display names of the units ParseTime understands
+var durationUnitNames = map[string]string{
+	"s":  "Second",
+	"m":  "Minute",
+	"h":  "Hour",
+	"d":  "Day",
+	"w":  "Week",
+	"mo": "Month",
+	"y":  "Year",
+}
+
+// createDisplayDurationString
+// Turn a time string into a human-readable list of its durations, e.g. "1d12h" becomes "1 Day & 12 Hours"
 func createDisplayDurationString(content string) (str string) {
 	// First tokenize
 	str = ""
@@ -291,63 +562,40 @@ func createDisplayDurationString(content string) (str string) {
 			prefixChar = ", "
 		}
 		// Grab only the letters out of the duration, to detect the unit
-		muteUnit := strings.ToLower(EnsureLetters(v))
+		unit, ok := durationUnitNames[strings.ToLower(EnsureLetters(v))]
+		if !ok {
+			continue
+		}
 
 		// Grab the number out of the duration
 		// Errors shouldn't be possible due to EnsureNumbers
 		multiplier, _ := strconv.Atoi(EnsureNumbers(v))
 
-		// clean this up
-		switch muteUnit {
-		case "s":
-			if multiplier > 1 {
-				str += prefixChar + fmt.Sprintf("%d Seconds", multiplier)
-				break
-			}
-			str += prefixChar + "Second"
-			break
-		case "m":
-			if multiplier > 1 {
-				str += prefixChar + fmt.Sprintf("%d Minutes", multiplier)
-				break
-			}
-			str += prefixChar + fmt.Sprintf("%d Minute", multiplier)
-			break
-		case "h":
-			if multiplier > 1 {
-				str += prefixChar + fmt.Sprintf("%d Hours", multiplier)
-				break
-			}
-			str += prefixChar + fmt.Sprintf("%d Hours", multiplier)
-			break
-		case "d":
-			if multiplier > 1 {
-				str += prefixChar + fmt.Sprintf("%d Days", multiplier)
-				break
-			}
-			str += prefixChar + fmt.Sprintf("%d Day", multiplier)
-			break
-		case "w":
-			if multiplier > 1 {
-				str += prefixChar + fmt.Sprintf("%d Weeks", multiplier)
-				break
-			}
-			str += prefixChar + fmt.Sprintf("%d Week", multiplier)
-			break
-		case "y":
-			if multiplier > 1 {
-				str += prefixChar + fmt.Sprintf("%d Years", multiplier)
-				break
-			}
-			str += prefixChar + fmt.Sprintf("%d Year", multiplier)
-			break
-		default:
-			break
+		if multiplier != 1 {
+			unit += "s"
 		}
+		str += prefixChar + fmt.Sprintf("%d %s", multiplier, unit)
 	}
 	return
 }
 
+// FormatDiscordTimestamp
+// Create the markup for a timestamp that Discord renders in the reader's own timezone
+// The style is one of t, T, d, D, f, F or R (relative, e.g. "in 2 hours"). Any other style uses Discord's default (f)
+func FormatDiscordTimestamp(t time.Time, style rune) string {
+	if !strings.ContainsRune("tTdDfFR", style) {
+		return fmt.Sprintf("<t:%d>", t.Unix())
+	}
+	return fmt.Sprintf("<t:%d:%c>", t.Unix(), style)
+}
+
+// RelativeTimestamp
+// Create the markup for a live relative timestamp that is the given duration from now, e.g. "in 2 hours"
+// This pairs with ParseTime, e.g. for "muted until ..." fields
+func RelativeTimestamp(d time.Duration) string {
+	return FormatDiscordTimestamp(time.Now().Add(d), 'R')
+}
+
 func FindAllString(re *regexp2.Regexp, s string) []string {
 	var matches []string
 	m, _ := re.FindStringMatch(s)
@@ -0,0 +1,225 @@
+package framework
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		input    string
+		duration time.Duration
+		display  string
+	}{
+		{"1s", time.Second, "1 Second"},
+		{"30s", 30 * time.Second, "30 Seconds"},
+		{"1m", time.Minute, "1 Minute"},
+		{"1h", time.Hour, "1 Hour"},
+		{"2h", 2 * time.Hour, "2 Hours"},
+		{"1d", day, "1 Day"},
+		{"1w", 7 * day, "1 Week"},
+		{"1y", 365 * day, "1 Year"},
+		{"2y", 2 * 365 * day, "2 Years"},
+		{"1d12h", day + 12*time.Hour, "1 Day & 12 Hours"},
+		{"1h2m1s", time.Hour + 2*time.Minute + time.Second, "1 Hour, 2 Minutes & 1 Second"},
+	}
+
+	for _, tt := range tests {
+		duration, display, err := ParseTime(tt.input)
+		if err != nil {
+			t.Errorf("ParseTime(%q) returned error: %s", tt.input, err)
+			continue
+		}
+		if duration != tt.duration {
+			t.Errorf("ParseTime(%q) duration = %s, want %s", tt.input, duration, tt.duration)
+		}
+		if display != tt.display {
+			t.Errorf("ParseTime(%q) display = %q, want %q", tt.input, display, tt.display)
+		}
+	}
+}
+
+func TestParseTimeInvalid(t *testing.T) {
+	for _, input := range []string{"", "abc", "h", "-"} {
+		if duration, display, err := ParseTime(input); err == nil {
+			t.Errorf("ParseTime(%q) = %s, %q; want an error", input, duration, display)
+		}
+	}
+}
+
+func TestParseTimeMonths(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		input    string
+		duration time.Duration
+		display  string
+	}{
+		{"1mo", 30 * day, "1 Month"},
+		{"2mo", 60 * day, "2 Months"},
+		{"1mo2w", 30*day + 14*day, "1 Month & 2 Weeks"},
+		{"5m", 5 * time.Minute, "5 Minutes"},
+		{"2mo5m", 60*day + 5*time.Minute, "2 Months & 5 Minutes"},
+	}
+
+	for _, tt := range tests {
+		duration, display, err := ParseTime(tt.input)
+		if err != nil {
+			t.Errorf("ParseTime(%q) returned error: %s", tt.input, err)
+			continue
+		}
+		if duration != tt.duration {
+			t.Errorf("ParseTime(%q) duration = %s, want %s", tt.input, duration, tt.duration)
+		}
+		if display != tt.display {
+			t.Errorf("ParseTime(%q) display = %q, want %q", tt.input, display, tt.display)
+		}
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain text", "plain text"},
+		{"**bold**", `\*\*bold\*\*`},
+		{"snake_case_name", `snake\_case\_name`},
+		{"`code`", "\\`code\\`"},
+		{"```block```", "\\`\\`\\`block\\`\\`\\`"},
+		{"~~gone~~ ||spoiler||", `\~\~gone\~\~ \|\|spoiler\|\|`},
+		{"> quote", `\> quote`},
+		{`back\slash*`, `back\\slash\*`},
+	}
+
+	for _, tt := range tests {
+		if got := EscapeMarkdown(tt.input); got != tt.want {
+			t.Errorf("EscapeMarkdown(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeCodeBlock(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"no backticks *here*", "no backticks *here*"},
+		{"`", "`\u200b"},
+		{"a```b", "a`\u200b`\u200b`\u200bb"},
+	}
+
+	for _, tt := range tests {
+		got := EscapeCodeBlock(tt.input)
+		if got != tt.want {
+			t.Errorf("EscapeCodeBlock(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if strings.Contains(got, "```") {
+			t.Errorf("EscapeCodeBlock(%q) still contains a code fence", tt.input)
+		}
+	}
+}
+
+func TestCommandUsageEscapesCodeBlocks(t *testing.T) {
+	info := CreateCommandInfo("say", "", true, "").
+		AddArg("text", String, ArgContent, "what ```to``` say", true, "")
+
+	usage := commandUsage("```", *info)
+	inner := strings.TrimSuffix(strings.TrimPrefix(usage, "```\n"), "\n```")
+	if strings.Contains(inner, "```") {
+		t.Errorf("commandUsage let a code fence through: %q", usage)
+	}
+	if !strings.HasPrefix(usage, "```\n") || !strings.HasSuffix(usage, "\n```") {
+		t.Errorf("commandUsage isn't wrapped in a code block: %q", usage)
+	}
+}
+
+func TestSanitizeMentions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"hello", "hello"},
+		{"@everyone look", "@\u200beveryone look"},
+		{"hey @here and @everyone", "hey @\u200bhere and @\u200beveryone"},
+		{"<@123456789012345678>", "<@123456789012345678>"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeMentions(tt.input); got != tt.want {
+			t.Errorf("SanitizeMentions(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		input     string
+		maxLength int
+		want      string
+	}{
+		{"short", 10, "short"},
+		{"exact", 5, "exact"},
+		{"too long", 5, "too …"},
+		{"héllo wörld", 6, "héllo…"},
+		{"ab", 1, "a"},
+		{"ab", 0, ""},
+		{"ab", -1, ""},
+	}
+
+	for _, tt := range tests {
+		got := Truncate(tt.input, tt.maxLength)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.input, tt.maxLength, got, tt.want)
+		}
+		if tt.maxLength >= 0 && len([]rune(got)) > tt.maxLength {
+			t.Errorf("Truncate(%q, %d) is %d characters long", tt.input, tt.maxLength, len([]rune(got)))
+		}
+	}
+}
+
+func TestRecoveredPanicsShareErrorReport(t *testing.T) {
+	oldWindow := errorReportWindow
+	SetErrorReportWindow(time.Hour)
+	t.Cleanup(func() { SetErrorReportWindow(oldWindow) })
+
+	var stacks []string
+	for i := 0; i < 3; i++ {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					stack := panicStack()
+					stacks = append(stacks, stack)
+					SendErrorReport("", "", "", "test panic", recoveredError(r), stack)
+				}
+			}()
+			var m map[string]int
+			m["boom"] = 1
+		}()
+	}
+
+	if !strings.Contains(stacks[0], "TestRecoveredPanicsShareErrorReport") {
+		t.Errorf("the stack trace doesn't contain the frame that panicked:\n%s", stacks[0])
+	}
+	if strings.Contains(stacks[0], "panicStack") {
+		t.Errorf("the stack trace contains the frames of the recovery:\n%s", stacks[0])
+	}
+
+	key := "test panic\x00assignment to entry in nil map"
+	pendingReportsLock.Lock()
+	report, ok := pendingReports[key]
+	if ok {
+		delete(pendingReports, key)
+	}
+	pendingReportsLock.Unlock()
+	if !ok {
+		t.Fatal("the panics didn't share an error report")
+	}
+	if report.occurrences != 3 {
+		t.Errorf("the error report counted %d occurrences, want 3", report.occurrences)
+	}
+	if report.detail != stacks[0] {
+		t.Error("the error report doesn't keep the stack trace of the first panic")
+	}
+}
@@ -0,0 +1,84 @@
+package framework
+
+import (
+	"errors"
+	"time"
+)
+
+// warnings.go
+// This file contains the warnings system, which stores member warnings in a guild's arbitrary storage
+
+// Warning
+// A single warning that was issued to a member
+type Warning struct {
+	Reason      string `json:"reason"`
+	ModeratorId string `json:"moderator_id"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// warningsKeyPrefix
+// The prefix of the storage key that holds a user's warnings; the user ID is appended to it
+const warningsKeyPrefix = "warnings_"
+
+// GetWarnings
+// Retrieve all the warnings a user has accumulated in this guild, oldest first
+func (g *Guild) GetWarnings(userId string) ([]Warning, error) {
+	cleanedId := CleanId(userId)
+	if cleanedId == "" {
+		return nil, errors.New("provided user ID is invalid")
+	}
+
+	var warnings []Warning
+	_, err := g.getStorageAs(warningsKeyPrefix+cleanedId, &warnings)
+	if err != nil {
+		return nil, errors.New("failed to cast the data to type \"[]Warning\"")
+	}
+
+	return warnings, nil
+}
+
+// AddWarning
+// Issue a warning to a user, then save the guild data
+// Returns the total amount of warnings the user now has
+func (g *Guild) AddWarning(userId string, moderatorId string, reason string) (int, error) {
+	cleanedId := CleanId(userId)
+	if cleanedId == "" {
+		return 0, errors.New("provided user ID is invalid")
+	}
+
+	// Hold the lock from reading the warnings until they are saved, so concurrent warnings aren't lost
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	var warnings []Warning
+	if _, err := g.getStorageAsLocked(warningsKeyPrefix+cleanedId, &warnings); err != nil {
+		return 0, errors.New("failed to cast the data to type \"[]Warning\"")
+	}
+
+	warnings = append(warnings, Warning{
+		Reason:      reason,
+		ModeratorId: moderatorId,
+		Timestamp:   time.Now().Unix(),
+	})
+
+	g.Info.Storage[warningsKeyPrefix+cleanedId] = warnings
+	return len(warnings), g.commitLocked()
+}
+
+// ClearWarnings
+// Remove all warnings from a user, then save the guild data
+func (g *Guild) ClearWarnings(userId string) error {
+	cleanedId := CleanId(userId)
+	if cleanedId == "" {
+		return errors.New("provided user ID is invalid")
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if _, ok := g.Info.Storage[warningsKeyPrefix+cleanedId]; !ok {
+		return errors.New("user has no warnings; nothing to clear")
+	}
+
+	delete(g.Info.Storage, warningsKeyPrefix+cleanedId)
+	return g.commitLocked()
+}
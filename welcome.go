@@ -0,0 +1,131 @@
+package framework
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// welcome.go
+// This file contains the opt-in welcome message and auto roles, which are applied when a member joins a guild
+// Discord only sends member join events to bots with the privileged server members intent
+
+// SetWelcomeChannel
+// Check that the channel exists, set the channel welcome messages are sent to, then save the guild data
+// A blank channelId disables welcome messages
+func (g *Guild) SetWelcomeChannel(channelId string) error {
+	if channelId == "" {
+		return g.update(func() {
+			g.Info.WelcomeChannelId = channelId
+		})
+	}
+	// Try grabbing the channel first (we don't use IsChannel since we need the real ID)
+	channel, err := g.GetChannel(channelId)
+	if err != nil {
+		return err
+	}
+	return g.update(func() {
+		g.Info.WelcomeChannelId = channel.ID
+	})
+}
+
+// SetWelcomeMessage
+// Set the message sent when a member joins, then save the guild data
+// "{user}" is replaced with a mention of the member, and "{guild}" with the name of the guild
+func (g *Guild) SetWelcomeMessage(message string) error {
+	return g.update(func() {
+		g.Info.WelcomeMessage = message
+	})
+}
+
+// IsAutoRole
+// Check if a given role ID is given to members when they join
+func (g *Guild) IsAutoRole(roleId string) bool {
+	for _, id := range g.Info.AutoRoleIds {
+		if id == roleId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddAutoRole
+// Add a role that is given to members when they join, then save the guild data
+func (g *Guild) AddAutoRole(roleId string) error {
+	role, err := g.GetRole(roleId)
+	if err != nil {
+		return err
+	}
+
+	if g.IsAutoRole(role.ID) {
+		return errors.New("role is already an auto role in this guild; nothing to add")
+	}
+
+	return g.update(func() {
+		g.Info.AutoRoleIds = append(g.Info.AutoRoleIds, role.ID)
+	})
+}
+
+// RemoveAutoRole
+// Remove a role from the roles given to members when they join, then save the guild data
+func (g *Guild) RemoveAutoRole(roleId string) error {
+	cleanedId := CleanId(roleId)
+	if cleanedId == "" {
+		return errors.New("provided ID is invalid")
+	}
+
+	if !g.IsAutoRole(cleanedId) {
+		return errors.New("role is not an auto role in this guild; nothing to remove")
+	}
+
+	return g.update(func() {
+		g.Info.AutoRoleIds = RemoveItem(g.Info.AutoRoleIds, cleanedId)
+	})
+}
+
+// formatWelcomeMessage
+// Fill in the placeholders of a welcome message
+func (g *Guild) formatWelcomeMessage(user *discordgo.User) string {
+	guildName := g.ID
+	if guild, err := SessionForGuild(g.ID).State.Guild(g.ID); err == nil {
+		guildName = guild.Name
+	}
+
+	return strings.NewReplacer(
+		"{user}", user.Mention(),
+		"{guild}", guildName,
+	).Replace(g.Info.WelcomeMessage)
+}
+
+// guildMemberAddHandler
+// Welcome a new member and give them the guild's auto roles, if either is configured
+func guildMemberAddHandler(session *discordgo.Session, event *discordgo.GuildMemberAdd) {
+	if event.User == nil || event.User.Bot {
+		return
+	}
+
+	g := getGuild(event.GuildID)
+
+	// Send the welcome message first, so a failing role assignment can't hold it up
+	if g.Info.WelcomeChannelId != "" && g.Info.WelcomeMessage != "" {
+		_, err := Session.ChannelMessageSendComplex(g.Info.WelcomeChannelId, &discordgo.MessageSend{
+			Content: g.formatWelcomeMessage(event.User),
+			AllowedMentions: &discordgo.MessageAllowedMentions{
+				Users: []string{event.User.ID},
+			},
+		})
+		if err != nil {
+			SendErrorReport(g.ID, g.Info.WelcomeChannelId, event.User.ID, "Failed to send welcome message", err)
+		}
+	}
+
+	for _, roleId := range g.Info.AutoRoleIds {
+		// This fails if the bot lacks Manage Roles, or if the role is above the bot's highest role
+		err := Session.GuildMemberRoleAdd(g.ID, event.User.ID, roleId)
+		if err != nil {
+			SendErrorReport(g.ID, "", event.User.ID, "Failed to assign auto role "+roleId, err)
+		}
+	}
+}
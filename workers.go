@@ -1,6 +1,10 @@
 package framework
 
 import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -14,42 +18,357 @@ import (
 // If a worker is still locked, then it has not exited
 var workerLock = make(map[int]*sync.Mutex)
 
+// workerRunLocks
+// A map of worker indexes to the mutex that is held while the worker runs
+// Unlike workerLock, which is held for as long as the worker is scheduled, this makes sure a worker never runs twice at once
+var workerRunLocks = make(map[int]*sync.Mutex)
+
+// workerRunLocksLock
+// Guards workerRunLocks, since workers can be run manually from any goroutine
+var workerRunLocksLock sync.Mutex
+
+// asyncWorkerRuns
+// The worker runs started by RunWorkerNowAsync, which the bot waits for before shutting down
+var asyncWorkerRuns sync.WaitGroup
+
+// asyncWorkerRunsLock
+// Makes sure no async run is started after the workers were stopped, since shutdown is already waiting for them
+var asyncWorkerRunsLock sync.Mutex
+
+// backgroundWorker
+// A worker function, and how often it should be run
+type backgroundWorker struct {
+	tag      string // The name of the worker, blank for workers added with AddWorker
+	run      func(ctx context.Context)
+	interval time.Duration
+	daily    bool          // Whether the worker runs once a day at a local time, instead of every interval
+	at       time.Duration // For daily workers, how long after midnight (in the worker timezone) the worker runs
+}
+
 // workers
 // The list of workers that are to be pre-registered before the bot starts, then all executed in the background
-var workers []func()
+var workers []backgroundWorker
+
+// defaultWorkerInterval
+// How often a worker is run if no interval is given to AddWorker
+const defaultWorkerInterval = time.Second
+
+// workerJitter
+// The maximum fraction of a worker's interval that is randomly added to each wait
+// This staggers the workers, so they don't all make API calls at the same moment
+const workerJitter = 0.1
 
-// continueLoop
-// This boolean will be changed to false when the bot is trying to shut down
-// All the background workers are looping on this being true, meaning they will stop when it is false
-var continueLoop = true
+// workerContext
+// The context passed to every worker. It is canceled when the bot is trying to shut down
+// All the background workers are looping until it is canceled, and long-running workers can watch it to abort mid-run
+var workerContext, cancelWorkers = context.WithCancel(context.Background())
 
 // AddWorker
 // Given a function that is passed through, append it to the list of worker functions
-func AddWorker(worker func()) {
-	workers = append(workers, worker)
+// The worker receives a context that is canceled when StopWorkers is called
+// An interval can optionally be given to control how often the worker runs; it defaults to once per second
+func AddWorker(worker func(ctx context.Context), interval ...time.Duration) {
+	every := defaultWorkerInterval
+	if len(interval) > 0 && interval[0] > 0 {
+		every = interval[0]
+	}
+
+	workers = append(workers, backgroundWorker{
+		run:      worker,
+		interval: every,
+	})
+}
+
+// AddIntervalWorker
+// Add a named worker that runs every interval, for simple tasks that don't need the worker context
+// Named workers can be looked up by their tag. The tag must be unique, and the interval must be positive
+// Like AddWorker, this must be called before the bot starts
+func AddIntervalWorker(tag string, every time.Duration, fn func()) error {
+	if tag == "" {
+		return errors.New("worker tag cannot be blank")
+	}
+	if every <= 0 {
+		return errors.New("worker interval must be positive")
+	}
+	if workerIndex(tag) != -1 {
+		return errors.New("a worker with the tag \"" + tag + "\" already exists")
+	}
+
+	workers = append(workers, backgroundWorker{
+		tag:      tag,
+		run:      func(ctx context.Context) { fn() },
+		interval: every,
+	})
+	return nil
+}
+
+// AddDailyWorker
+// Add a named worker that runs once a day at the given local time, e.g. 9, 0 for "every day at 9am"
+// The time is in the worker timezone (see SetWorkerTimezone), and daylight saving time is taken into account
+// Unlike interval workers, daily workers don't run when the bot starts, only at their time
+// Like AddWorker, this must be called before the bot starts
+func AddDailyWorker(tag string, hour int, minute int, fn func()) error {
+	if tag == "" {
+		return errors.New("worker tag cannot be blank")
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return errors.New("daily worker time must be between 00:00 and 23:59")
+	}
+	if workerIndex(tag) != -1 {
+		return errors.New("a worker with the tag \"" + tag + "\" already exists")
+	}
+
+	workers = append(workers, backgroundWorker{
+		tag:   tag,
+		run:   func(ctx context.Context) { fn() },
+		daily: true,
+		at:    time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute,
+	})
+	return nil
+}
+
+// nextDailyRun
+// Get the next time after now that a daily worker should run, in the worker timezone
+func nextDailyRun(now time.Time, at time.Duration) time.Time {
+	now = now.In(workerLocation)
+	hour, minute := int(at/time.Hour), int(at%time.Hour/time.Minute)
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, workerLocation)
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, hour, minute, 0, 0, workerLocation)
+	}
+	return next
+}
+
+// workerIndex
+// Get the index of the worker with the given tag, or -1 if there is none
+func workerIndex(tag string) int {
+	for i, worker := range workers {
+		if worker.tag != "" && worker.tag == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+// workerName
+// Get the name of a worker for logs and reports, which is its tag, or its index if it has no tag
+func workerName(worker backgroundWorker, i int) string {
+	if worker.tag != "" {
+		return worker.tag
+	}
+	return strconv.Itoa(i)
+}
+
+// jitter
+// Return a random duration between 0 and workerJitter of the given interval
+func jitter(interval time.Duration) time.Duration {
+	maxJitter := int64(float64(interval) * workerJitter)
+	if maxJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(maxJitter))
+}
+
+// workersRunning
+// Whether startWorkers has already been called, so the workers aren't started twice
+var workersRunning = false
+
+// workerLocation
+// The timezone that workers use to decide when to run. Defaults to UTC
+var workerLocation = time.UTC
+
+// SetWorkerTimezone
+// Set the timezone that workers use, so schedules can be expressed in local time (e.g. "9am Eastern")
+// This decides when daily workers (see AddDailyWorker) run, and what WorkerTime returns
+// Interval workers run every interval regardless of the timezone
+// If the location is nil, UTC is used instead
+func SetWorkerTimezone(loc *time.Location) {
+	if loc == nil {
+		log.Warning("Worker timezone cannot be nil; falling back to UTC")
+		loc = time.UTC
+	}
+	workerLocation = loc
+}
+
+// WorkerTime
+// Get the current time in the configured worker timezone
+// Workers can use this to check if it is time for a scheduled task in the configured region
+func WorkerTime() time.Time {
+	return time.Now().In(workerLocation)
+}
+
+// StopWorkers
+// Cancel the context of all background workers, so they stop after their current run
+func StopWorkers() {
+	asyncWorkerRunsLock.Lock()
+	cancelWorkers()
+	asyncWorkerRunsLock.Unlock()
+}
+
+// workerRunLock
+// Get the mutex that is held while the worker with the given index runs, creating it if needed
+func workerRunLock(i int) *sync.Mutex {
+	workerRunLocksLock.Lock()
+	defer workerRunLocksLock.Unlock()
+	if workerRunLocks[i] == nil {
+		workerRunLocks[i] = &sync.Mutex{}
+	}
+	return workerRunLocks[i]
+}
+
+// WorkerStat
+// How a worker has been running, so stuck or failing workers can be spotted
+type WorkerStat struct {
+	LastRun      time.Time     // When the worker last started running
+	LastDuration time.Duration // How long the last run took
+	RunCount     int64         // How many times the worker has run
+	LastError    error         // The panic from the last run, or nil if it finished normally
+}
+
+// workerStats
+// A map of worker indexes to their stats, filled in as the workers run
+var workerStats = make(map[int]WorkerStat)
+
+// workerStatsLock
+// Guards workerStats, since the workers run in parallel
+var workerStatsLock sync.Mutex
+
+// WorkerStats
+// Get the stats of every worker that has run at least once, keyed by the worker's tag (or index, if it has no tag)
+func WorkerStats() map[string]WorkerStat {
+	workerStatsLock.Lock()
+	defer workerStatsLock.Unlock()
+
+	stats := make(map[string]WorkerStat, len(workerStats))
+	for i, stat := range workerStats {
+		stats[workerName(workers[i], i)] = stat
+	}
+	return stats
+}
+
+// recordWorkerRun
+// Update the stats of a worker after a run
+func recordWorkerRun(i int, started time.Time, err error) {
+	workerStatsLock.Lock()
+	defer workerStatsLock.Unlock()
+
+	stat := workerStats[i]
+	stat.LastRun = started
+	stat.LastDuration = time.Since(started)
+	stat.RunCount++
+	stat.LastError = err
+	workerStats[i] = stat
+}
+
+// runWorker
+// Run a worker a single time, recovering from any panic so the worker keeps running on its next tick
+// If the worker is already running, e.g. because it was also run manually, this waits for that run to finish first
+// Returns the panic as an error, if there was one
+func runWorker(worker backgroundWorker, i int) (err error) {
+	lock := workerRunLock(i)
+	lock.Lock()
+	defer lock.Unlock()
+
+	started := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Warningf("Recovering from panic in worker %s: %s", workerName(worker, i), r)
+			log.Warningf("Sending Error report to admins")
+			err = recoveredError(r)
+			SendErrorReport("", "", "", "Worker "+workerName(worker, i)+" panicked", err, panicStack())
+		}
+		recordWorkerRun(i, started, err)
+	}()
+
+	worker.run(workerContext)
+	return nil
+}
+
+// RunWorkerNow
+// Run the named worker right away and wait for it to finish, e.g. from an admin command
+// If the worker is already running, it runs again once that run is done
+// The worker's regular schedule is not changed. Returns an error if there is no such worker, or if it panicked
+func RunWorkerNow(tag string) error {
+	i := workerIndex(tag)
+	if i == -1 {
+		return errors.New("there is no worker with the tag \"" + tag + "\"")
+	}
+	return runWorker(workers[i], i)
+}
+
+// RunWorkerNowAsync
+// Like RunWorkerNow, but runs the worker in the background and returns immediately, for long-running workers
+// The bot waits for the run to finish before shutting down
+// Returns an error if there is no such worker, or if the workers are stopping; panics are still reported to the bot admins
+func RunWorkerNowAsync(tag string) error {
+	i := workerIndex(tag)
+	if i == -1 {
+		return errors.New("there is no worker with the tag \"" + tag + "\"")
+	}
+
+	asyncWorkerRunsLock.Lock()
+	defer asyncWorkerRunsLock.Unlock()
+	if workerContext.Err() != nil {
+		return errors.New("workers are stopping; not starting worker \"" + tag + "\"")
+	}
+
+	asyncWorkerRuns.Add(1)
+	go func() {
+		defer asyncWorkerRuns.Done()
+		_ = runWorker(workers[i], i)
+	}()
+	return nil
+}
+
+// workerSleep
+// Wait for the given duration, but stop waiting immediately if the worker context is canceled
+// Returns false if the context was canceled
+func workerSleep(d time.Duration) bool {
+	select {
+	case <-workerContext.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
 }
 
 // startWorkers
 // Go through the list of workers than have been added to the list, and execute them all in the background
 func startWorkers() {
+	if workersRunning {
+		log.Warning("Workers have already been started; not starting them again")
+		return
+	}
+	workersRunning = true
+
 	// Iterate over all the workers
 	for i, worker := range workers {
 		// Create a mutex for this worker
 		workerLock[i] = &sync.Mutex{}
 
 		// Start a goroutine for this worker, which starts it in the background
-		go func(worker func(), i int) {
+		go func(worker backgroundWorker, i int) {
 			// Lock the worker; this will be used in graceful termination
 			workerLock[i].Lock()
 
-			// Run the worker once per second, forever, until a TERM signal breaks this loop
-			for continueLoop {
-				worker()
-				time.Sleep(time.Second)
+			// Run the worker once per interval, forever, until a TERM signal cancels the context
+			// Daily workers wait for their time first instead, since they shouldn't run at startup
+			for workerContext.Err() == nil {
+				if worker.daily && !workerSleep(time.Until(nextDailyRun(time.Now(), worker.at))) {
+					break
+				}
+
+				_ = runWorker(worker, i)
+
+				if !worker.daily && !workerSleep(worker.interval+jitter(worker.interval)) {
+					break
+				}
 			}
 
 			// The loop has stopped. Unlock the worker
 			workerLock[i].Unlock()
 		}(worker, i)
 	}
+
+	log.Infof("Started %d worker(s)", len(workers))
 }
@@ -0,0 +1,46 @@
+package framework
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunWorkerNowDoesNotOverlap(t *testing.T) {
+	oldWorkers := workers
+	t.Cleanup(func() { workers = oldWorkers })
+
+	var running, maxRunning, runs int32
+	err := AddIntervalWorker("test-overlap", time.Hour, func() {
+		now := atomic.AddInt32(&running, 1)
+		for {
+			prev := atomic.LoadInt32(&maxRunning)
+			if now <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, now) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&runs, 1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := RunWorkerNowAsync("test-overlap"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RunWorkerNow("test-overlap"); err != nil {
+		t.Fatal(err)
+	}
+	asyncWorkerRuns.Wait()
+
+	if got := atomic.LoadInt32(&runs); got != 6 {
+		t.Errorf("the worker ran %d times, want 6", got)
+	}
+	if got := atomic.LoadInt32(&maxRunning); got != 1 {
+		t.Errorf("%d runs of the worker overlapped", got)
+	}
+}